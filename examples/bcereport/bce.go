@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+// check is a bounds check which the compiler could not eliminate.
+type check struct {
+	pos   gcdiag.Pos
+	fn    string
+	slice bool   // a slice expression, rather than an index expression
+	src   string // the source line, with leading and trailing space removed
+}
+
+func (c check) kind() string {
+	if c.slice {
+		return "slice"
+	}
+	return "index"
+}
+
+// checks extracts the remaining bounds checks from the output of
+// -gcflags=-d=ssa/check_bce/debug=1, which reports each one as
+//
+//	./bounds_test.go:13:8: Found IsInBounds
+//
+// for an index expression, or IsSliceInBounds for a slice expression.
+func checks(diags []gcdiag.Diagnostic, funcs *gcdiag.Funcs) []check {
+	var cs []check
+	for _, d := range diags {
+		var slice bool
+		switch d.Message {
+		case "Found IsInBounds":
+		case "Found IsSliceInBounds":
+			slice = true
+		default:
+			continue
+		}
+		cs = append(cs, check{
+			pos:   d.Pos,
+			fn:    funcs.At(d.Pos),
+			slice: slice,
+			src:   strings.TrimSpace(funcs.Line(d.Pos)),
+		})
+	}
+	return cs
+}
+
+// summary is the number of bounds checks in a function.
+type summary struct {
+	fn           string
+	index, slice int
+}
+
+func (s summary) total() int { return s.index + s.slice }
+
+// summarise counts the checks in each function, in the order in which
+// the functions first appear in cs.
+func summarise(cs []check) []summary {
+	var ss []summary
+	idx := make(map[string]int)
+	for _, c := range cs {
+		i, ok := idx[c.fn]
+		if !ok {
+			i = len(ss)
+			idx[c.fn] = i
+			ss = append(ss, summary{fn: c.fn})
+		}
+		if c.slice {
+			ss[i].slice++
+		} else {
+			ss[i].index++
+		}
+	}
+	return ss
+}
+
+// writeReport writes the number of checks in each function and, if
+// verbose, the checks themselves.
+func writeReport(w io.Writer, cs []check, verbose bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "FUNCTION\tCHECKS\tINDEX\tSLICE\n")
+	for _, s := range summarise(cs) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", name(s.fn), s.total(), s.index, s.slice)
+		if !verbose {
+			continue
+		}
+		for _, c := range cs {
+			if c.fn == s.fn {
+				fmt.Fprintf(tw, "  %s\t%s\t\t\t%s\n", c.pos, c.kind(), c.src)
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// name returns fn, or a placeholder for checks outside any function, such
+// as in a package level variable's initialiser.
+func name(fn string) string {
+	if fn == "" {
+		return "(package)"
+	}
+	return fn
+}
+
+// key identifies a check across two versions of a package. It uses the
+// source line, not its number, so that unrelated edits above it do not
+// make it appear to have moved.
+type key struct {
+	file, fn, kind, src string
+}
+
+func keyOf(c check) key {
+	return key{filepath.Base(c.pos.File), c.fn, c.kind(), c.src}
+}
+
+// diff returns the checks in before but not after, and those in after
+// but not before. Checks with the same key are matched in order.
+func diff(before, after []check) (removed, added []check) {
+	return unmatched(before, after), unmatched(after, before)
+}
+
+// unmatched returns the checks in a which are left over when each check
+// in b is matched with the first unmatched check in a with the same key.
+func unmatched(a, b []check) []check {
+	n := make(map[key]int)
+	for _, c := range b {
+		n[keyOf(c)]++
+	}
+	var cs []check
+	for _, c := range a {
+		if k := keyOf(c); n[k] > 0 {
+			n[k]--
+			continue
+		}
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+// writeDiff writes the number of checks in each function in before and
+// after, for the functions in which it changed, then the checks removed,
+// prefixed with "-", and those added, prefixed with "+".
+func writeDiff(w io.Writer, before, after []check) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "FUNCTION\tBEFORE\tAFTER\tDELTA\n")
+	counts := make(map[string][2]int)
+	var fns []string
+	for i, cs := range [][]check{before, after} {
+		for _, s := range summarise(cs) {
+			n, ok := counts[s.fn]
+			if !ok {
+				fns = append(fns, s.fn)
+			}
+			n[i] = s.total()
+			counts[s.fn] = n
+		}
+	}
+	for _, fn := range fns {
+		n := counts[fn]
+		if n[0] != n[1] {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%+d\n", name(fn), n[0], n[1], n[1]-n[0])
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	removed, added := diff(before, after)
+	if len(removed)+len(added) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	for _, c := range removed {
+		fmt.Fprintf(tw, "-\t%s\t%s\t%s\t%s\n", c.pos, name(c.fn), c.kind(), c.src)
+	}
+	for _, c := range added {
+		fmt.Fprintf(tw, "+\t%s\t%s\t%s\t%s\n", c.pos, name(c.fn), c.kind(), c.src)
+	}
+	return tw.Flush()
+}
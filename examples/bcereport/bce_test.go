@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+func TestChecks(t *testing.T) {
+	const output = `# github.com/grafana/high-performance-go-workshop/examples/bounds [github.com/grafana/high-performance-go-workshop/examples/bounds.test]
+../bounds/bounds_test.go:13:8: Found IsInBounds
+../bounds/bounds_test.go:32:8: Found IsInBounds
+../bounds/bounds_test.go:33:8: Found IsSliceInBounds
+../bounds/bounds_test.go:34:8: Found SomethingElse
+`
+	diags, err := gcdiag.Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := checks(diags, new(gcdiag.Funcs))
+	pos := func(line int) gcdiag.Pos { return gcdiag.Pos{File: "../bounds/bounds_test.go", Line: line, Col: 8} }
+	want := []check{
+		{pos(13), "BenchmarkBoundsCheckInOrder", false, "a = v[0]"},
+		{pos(32), "BenchmarkBoundsCheckOutOfOrder", false, "i = v[8]"},
+		{pos(33), "BenchmarkBoundsCheckOutOfOrder", true, "a = v[0]"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+	wantSummary := []summary{
+		{"BenchmarkBoundsCheckInOrder", 1, 0},
+		{"BenchmarkBoundsCheckOutOfOrder", 1, 1},
+	}
+	if got := summarise(got); !reflect.DeepEqual(got, wantSummary) {
+		t.Fatalf("summarise: got %v, want %v", got, wantSummary)
+	}
+}
+
+// TestBounds checks the reference case: reading v[8] first proves the
+// other eight reads are in bounds.
+func TestBounds(t *testing.T) {
+	cs, err := load("../bounds", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int)
+	for _, s := range summarise(cs) {
+		got[s.fn] = s.total()
+	}
+	want := map[string]int{
+		"BenchmarkBoundsCheckInOrder":    9,
+		"BenchmarkBoundsCheckOutOfOrder": 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before, err := load("./testdata/inorder", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := load("./testdata/outoforder", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, added := diff(before, after)
+	if len(added) != 0 {
+		t.Errorf("added: %v, want none", added)
+	}
+	var srcs []string
+	for _, c := range removed {
+		srcs = append(srcs, c.src)
+	}
+	want := []string{"a = v[0]", "_b = v[1]", "c = v[2]", "d = v[3]", "e = v[4]", "f = v[5]", "g = v[6]", "h = v[7]"}
+	if !reflect.DeepEqual(srcs, want) {
+		t.Errorf("removed: got %q, want %q", srcs, want)
+	}
+
+	var buf bytes.Buffer
+	if err := writeDiff(&buf, before, after); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "FUNCTION              BEFORE  AFTER  DELTA\nBenchmarkBoundsCheck  9       1      -8\n") {
+		t.Errorf("unexpected diff:\n%s", &buf)
+	}
+}
+
+// TestLoadFile checks that a single .go file is compiled, rather than read
+// as saved compiler output.
+func TestLoadFile(t *testing.T) {
+	cs, err := load("testdata/single/single.go", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cs {
+		if c.fn == "last" && c.src == "return s[len(s)-1]" {
+			return
+		}
+	}
+	t.Fatalf("bounds check in last not reported in %v", cs)
+}
+
+// TestLoadSaved follows the documented workflow: save -raw output, move
+// the read of v[8] to the top of the loop, then diff. Renaming the files
+// in the saved output stands in for editing them in place, so the checks
+// must be labelled from the saved output, not the edited source.
+func TestLoadSaved(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeRaw(&buf, "./testdata/inorder", true); err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.ReplaceAll(buf.String(), "testdata/inorder/", "testdata/outoforder/")
+	saved := filepath.Join(t.TempDir(), "before.txt")
+	if err := os.WriteFile(saved, []byte(edited), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	before, err := load(saved, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := load("./testdata/outoforder", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, added := diff(before, after)
+	if len(added) != 0 {
+		t.Errorf("added: %v, want none", added)
+	}
+	// Each check must keep the line it was on as well as its source.
+	var got []string
+	for _, c := range removed {
+		got = append(got, fmt.Sprintf("%d: %s", c.pos.Line, c.src))
+	}
+	want := []string{"13: a = v[0]", "14: _b = v[1]", "15: c = v[2]", "16: d = v[3]", "17: e = v[4]", "18: f = v[5]", "19: g = v[6]", "20: h = v[7]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("removed: got %q, want %q", got, want)
+	}
+}
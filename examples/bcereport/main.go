@@ -0,0 +1,136 @@
+// bcereport reports the bounds checks the compiler could not eliminate.
+//
+// It compiles a package with -gcflags=-d=ssa/check_bce/debug=1 and prints
+// the number of bounds checks left in each function. With -v it lists
+// each check with its source line.
+//
+//	go run ./examples/bcereport -test -v ./examples/bounds
+//
+// With -raw it prints the compiler output instead, followed by the
+// function and text of each source line it mentions, which can be saved
+// and compared with a later version of the package using -diff:
+//
+//	go run ./examples/bcereport -test -raw ./examples/bounds > before.txt
+//	# reorder the reads from v
+//	go run ./examples/bcereport -test -diff before.txt ./examples/bounds
+//
+// Each argument to -diff is a file of saved compiler output, or a package
+// or single .go file to compile. The diff shows how the number of checks in each
+// function changed, then the checks which were removed or added,
+// matched by their source rather than line number.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+const gcflags = "-d=ssa/check_bce/debug=1"
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("bcereport: ")
+	raw := flag.Bool("raw", false, "print the compiler output rather than the report")
+	diffMode := flag.Bool("diff", false, "compare two versions: bcereport -diff before after")
+	tests := flag.Bool("test", false, "compile the package's test files too")
+	verbose := flag.Bool("v", false, "list each bounds check")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: bcereport [-raw] [-test] [-v] [package]\n")
+		fmt.Fprintf(os.Stderr, "       bcereport -diff [-test] before after\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *diffMode {
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		before, err := load(flag.Arg(0), *tests)
+		if err != nil {
+			log.Fatal(err)
+		}
+		after, err := load(flag.Arg(1), *tests)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := writeDiff(os.Stdout, before, after); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	pkg := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		pkg = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *raw {
+		if err := writeRaw(os.Stdout, pkg, *tests); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	cs, err := load(pkg, *tests)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writeReport(os.Stdout, cs, *verbose); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeRaw writes the compiler output for pkg, followed by the function
+// and text of each line in it with a bounds check, so that load need not
+// look them up in source which has been edited since.
+func writeRaw(w io.Writer, pkg string, tests bool) error {
+	out, err := gcdiag.Compile(pkg, tests, gcflags)
+	if err != nil {
+		return err
+	}
+	diags, err := gcdiag.Parse(bytes.NewReader(out))
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(out); err != nil {
+		return err
+	}
+	return new(gcdiag.Funcs).Write(w, diags)
+}
+
+// load returns the bounds checks for arg, which is a file of output saved
+// by -raw, or a package or single .go file to compile.
+func load(arg string, tests bool) ([]check, error) {
+	var out []byte
+	funcs := new(gcdiag.Funcs)
+	if fi, err := os.Stat(arg); err == nil && fi.Mode().IsRegular() && !strings.HasSuffix(arg, ".go") {
+		out, err = os.ReadFile(arg)
+		if err != nil {
+			return nil, err
+		}
+		if err := funcs.Read(bytes.NewReader(out)); err != nil {
+			return nil, err
+		}
+	} else {
+		out, err = gcdiag.Compile(arg, tests, gcflags)
+		if err != nil {
+			return nil, err
+		}
+	}
+	diags, err := gcdiag.Parse(bytes.NewReader(out))
+	if err != nil {
+		return nil, err
+	}
+	return checks(diags, funcs), nil
+}
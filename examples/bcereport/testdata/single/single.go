@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+// last has one bounds check, which the compiler cannot remove because s
+// may be empty.
+func last(s []string) string {
+	return s[len(s)-1]
+}
+
+func main() {
+	println(last(os.Args))
+}
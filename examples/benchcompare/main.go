@@ -0,0 +1,174 @@
+// benchcompare compares the results of go test -bench, like benchstat,
+// without needing anything outside this repository.
+//
+// Given two files it compares each benchmark in the first with the
+// benchmark of the same name in the second:
+//
+//	go test -bench=Fib20 -count=10 ./examples/fib/ > old.txt
+//	# edit Fib
+//	go test -bench=Fib20 -count=10 ./examples/fib/ > new.txt
+//	go run ./examples/benchcompare old.txt new.txt
+//
+// With -pair it compares two benchmarks in the same results instead:
+//
+//	go test -bench='Range|For' -count=10 ./examples/range/ > range.txt
+//	go run ./examples/benchcompare -pair BenchmarkRange,BenchmarkFor range.txt
+//
+// For each unit it prints the median of each set of samples, with the
+// half width of its 95% confidence interval as a percentage, and the
+// change between them if the Mann-Whitney U test finds it significant.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("benchcompare: ")
+	pair := flag.String("pair", "", "compare the benchmarks `old,new` rather than two files")
+	alpha := flag.Float64("alpha", 0.05, "consider changes with a p-value below `α` significant")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: benchcompare [flags] old.txt [new.txt]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var before, after *samples
+	var err error
+	switch flag.NArg() {
+	case 1:
+		before, err = parseFile(flag.Arg(0))
+		after = before
+	case 2:
+		if before, err = parseFile(flag.Arg(0)); err == nil {
+			after, err = parseFile(flag.Arg(1))
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var pairs [][2]string
+	if *pair != "" {
+		a, b, ok := strings.Cut(*pair, ",")
+		if !ok {
+			log.Fatalf("-pair: want old,new, got %q", *pair)
+		}
+		pairs = [][2]string{{a, b}}
+	} else if flag.NArg() == 1 {
+		log.Fatal("comparing a single file needs -pair")
+	} else {
+		for _, name := range before.names {
+			pairs = append(pairs, [2]string{name, name})
+		}
+	}
+	compare(os.Stdout, before, after, pairs, *alpha)
+}
+
+func parseFile(path string) (*samples, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+// compare prints a table per unit comparing each pair of benchmarks.
+func compare(w io.Writer, before, after *samples, pairs [][2]string, alpha float64) {
+	units := slices.Clone(before.units)
+	for _, u := range after.units {
+		if !slices.Contains(units, u) {
+			units = append(units, u)
+		}
+	}
+	for i, unit := range units {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		rows := 0
+		for _, p := range pairs {
+			xs, ys := before.values[key{p[0], unit}], after.values[key{p[1], unit}]
+			if len(xs) == 0 || len(ys) == 0 {
+				continue
+			}
+			if rows == 0 {
+				if i > 0 {
+					fmt.Fprintln(w)
+				}
+				fmt.Fprintf(tw, "name\told %s\tnew %s\tdelta\n", unit, unit)
+			}
+			rows++
+			name := strings.TrimPrefix(p[0], "Benchmark")
+			if p[0] != p[1] {
+				name += " vs " + strings.TrimPrefix(p[1], "Benchmark")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, summary(xs, unit), summary(ys, unit), delta(xs, ys, alpha))
+		}
+		tw.Flush()
+	}
+}
+
+// summary formats the median of xs and the half width of its confidence
+// interval. At least six samples are needed for a 95% interval.
+func summary(xs []float64, unit string) string {
+	xs = slices.Sorted(slices.Values(xs))
+	m := median(xs)
+	lo, hi, ok := medianCI(xs, 0.95)
+	s := format(m, unit)
+	switch {
+	case !ok:
+		s += " ± ∞" // too few samples for a 95% confidence interval
+	case m != 0:
+		s += fmt.Sprintf(" ± %.0f%%", math.Max(m-lo, hi-m)/math.Abs(m)*100)
+	}
+	return s
+}
+
+// delta formats the change in median from xs to ys, or ~ if the change is
+// not significant.
+func delta(xs, ys []float64, alpha float64) string {
+	p := mannWhitneyU(xs, ys)
+	stats := fmt.Sprintf("(p=%.3f n=%d+%d)", p, len(xs), len(ys))
+	mx := median(slices.Sorted(slices.Values(xs)))
+	my := median(slices.Sorted(slices.Values(ys)))
+	if p >= alpha || mx == 0 {
+		return "~ " + stats
+	}
+	return fmt.Sprintf("%+.2f%% %s", (my-mx)/mx*100, stats)
+}
+
+// format formats v with three significant figures, scaling times to a
+// suitable unit and other values by an SI prefix.
+func format(v float64, unit string) string {
+	if unit == "ns/op" {
+		for _, u := range []struct {
+			scale float64
+			name  string
+		}{{1e9, "s"}, {1e6, "ms"}, {1e3, "µs"}} {
+			if math.Abs(v) >= u.scale {
+				return fmt.Sprintf("%.3g%s", v/u.scale, u.name)
+			}
+		}
+		return fmt.Sprintf("%.3gns", v)
+	}
+	for _, u := range []struct {
+		scale  float64
+		prefix string
+	}{{1e9, "G"}, {1e6, "M"}, {1e3, "k"}} {
+		if math.Abs(v) >= u.scale {
+			return fmt.Sprintf("%.3g%s", v/u.scale, u.prefix)
+		}
+	}
+	return fmt.Sprintf("%.3g", v)
+}
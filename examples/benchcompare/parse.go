@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// samples holds the measurements for each benchmark and unit, in the order
+// benchmarks and units were first seen.
+type samples struct {
+	names  []string
+	units  []string
+	values map[key][]float64
+}
+
+type key struct {
+	name, unit string
+}
+
+// parse reads the output of go test -bench, collecting every measurement,
+// including those from -benchmem and b.ReportMetric. Lines which are not
+// benchmark results are ignored.
+func parse(r io.Reader) (*samples, error) {
+	s := &samples{values: make(map[key][]float64)}
+	seenName := make(map[string]bool)
+	seenUnit := make(map[string]bool)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		name, ms, ok := parseLine(sc.Text())
+		if !ok {
+			continue
+		}
+		if !seenName[name] {
+			seenName[name] = true
+			s.names = append(s.names, name)
+		}
+		for _, m := range ms {
+			if !seenUnit[m.unit] {
+				seenUnit[m.unit] = true
+				s.units = append(s.units, m.unit)
+			}
+			k := key{name, m.unit}
+			s.values[k] = append(s.values[k], m.value)
+		}
+	}
+	return s, sc.Err()
+}
+
+type measurement struct {
+	value float64
+	unit  string
+}
+
+// parseLine parses a result line such as
+//
+//	BenchmarkFib20-8   30099   38117 ns/op   0 B/op   0 allocs/op
+func parseLine(line string) (string, []measurement, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+		return "", nil, false
+	}
+	if _, err := strconv.Atoi(fields[1]); err != nil {
+		return "", nil, false
+	}
+	var ms []measurement
+	for i := 2; i < len(fields); i += 2 {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return "", nil, false
+		}
+		ms = append(ms, measurement{v, fields[i+1]})
+	}
+	return fields[0], ms, true
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const fibOld = `goos: linux
+goarch: amd64
+pkg: github.com/grafana/high-performance-go-workshop/examples/fib
+BenchmarkFib20-8   	   30099	     38117 ns/op	       0 B/op	       0 allocs/op
+BenchmarkFib20-8   	   31806	     40433 ns/op	       0 B/op	       0 allocs/op
+BenchmarkFib20-8   	   30052	     43412 ns/op	       0 B/op	       0 allocs/op
+BenchmarkFib20-8   	   28392	     39225 ns/op	       0 B/op	       0 allocs/op
+BenchmarkFib20-8   	   28270	     42956 ns/op	       0 B/op	       0 allocs/op
+BenchmarkFib20-8   	   28276	     49493 ns/op	       0 B/op	       0 allocs/op
+BenchmarkSeries/interned-8   	3	 237277020 ns/op	       201.0 live-B/series
+--- FAIL: BenchmarkBroken
+PASS
+ok  	github.com/grafana/high-performance-go-workshop/examples/fib	12.345s
+`
+
+func TestParse(t *testing.T) {
+	s, err := parse(strings.NewReader(fibOld))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"BenchmarkFib20-8", "BenchmarkSeries/interned-8"}; !reflect.DeepEqual(s.names, want) {
+		t.Fatalf("names: want %q, got %q", want, s.names)
+	}
+	if want := []string{"ns/op", "B/op", "allocs/op", "live-B/series"}; !reflect.DeepEqual(s.units, want) {
+		t.Fatalf("units: want %q, got %q", want, s.units)
+	}
+	want := []float64{38117, 40433, 43412, 39225, 42956, 49493}
+	if got := s.values[key{"BenchmarkFib20-8", "ns/op"}]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ns/op: want %v, got %v", want, got)
+	}
+	if got := s.values[key{"BenchmarkSeries/interned-8", "live-B/series"}]; !reflect.DeepEqual(got, []float64{201}) {
+		t.Fatalf("live-B/series: got %v", got)
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"BenchmarkFib20-8",
+		"BenchmarkFib20-8 100",
+		"BenchmarkFib20-8 x 10 ns/op",
+		"BenchmarkFib20-8 100 ten ns/op",
+		"BenchmarkFib20-8 100 10 ns/op 0",
+		"--- BENCH: BenchmarkFib20-8",
+		"    fib_test.go:12: some log output",
+	} {
+		if _, _, ok := parseLine(line); ok {
+			t.Errorf("parseLine(%q): want not ok", line)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	before, err := parse(strings.NewReader(fibOld))
+	if err != nil {
+		t.Fatal(err)
+	}
+	faster := strings.NewReplacer("38117", "19117", "40433", "20433", "43412", "21412", "39225", "19225", "42956", "20956", "49493", "24493")
+	after, err := parse(strings.NewReader(faster.Replace(fibOld)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	compare(&buf, before, after, [][2]string{{"BenchmarkFib20-8", "BenchmarkFib20-8"}}, 0.05)
+	want := `name     old ns/op     new ns/op     delta
+Fib20-8  41.7µs ± 19%  20.7µs ± 18%  -50.37% (p=0.002 n=6+6)
+
+name     old B/op  new B/op  delta
+Fib20-8  0         0         ~ (p=1.000 n=6+6)
+
+name     old allocs/op  new allocs/op  delta
+Fib20-8  0              0              ~ (p=1.000 n=6+6)
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// median returns the median of xs, which must be sorted.
+func median(xs []float64) float64 {
+	n := len(xs)
+	if n%2 == 1 {
+		return xs[n/2]
+	}
+	return (xs[n/2-1] + xs[n/2]) / 2
+}
+
+// medianCI returns a distribution free confidence interval for the median
+// of the population xs, which must be sorted, was drawn from. The interval
+// is bounded by order statistics chosen so that, by the binomial
+// distribution, it covers the median with at least the given confidence.
+// If xs is too small to reach that confidence, the full range of xs is
+// returned and ok is false.
+func medianCI(xs []float64, confidence float64) (lo, hi float64, ok bool) {
+	n := len(xs)
+	// widen the interval [xs[k], xs[n-1-k]] one order statistic at a time
+	// until it covers the median with enough probability. The interval
+	// misses the median when k or fewer samples fall on one side of it.
+	for k := (n - 1) / 2; k >= 0; k-- {
+		miss := 2 * binomialCDF(k, n)
+		if 1-miss >= confidence {
+			return xs[k], xs[n-1-k], true
+		}
+	}
+	return xs[0], xs[n-1], false
+}
+
+// binomialCDF returns P(X <= k) for X ~ Binomial(n, 1/2).
+func binomialCDF(k, n int) float64 {
+	var p float64
+	for i := 0; i <= k; i++ {
+		p += math.Exp(lchoose(n, i) - float64(n)*math.Ln2)
+	}
+	return p
+}
+
+func lchoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// mannWhitneyU returns the two sided p-value of the Mann-Whitney U test
+// of the hypothesis that xs and ys are drawn from the same distribution.
+// The exact distribution of U is used for small samples without ties,
+// otherwise the normal approximation with a tie correction.
+func mannWhitneyU(xs, ys []float64) float64 {
+	n1, n2 := len(xs), len(ys)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	// rank the combined samples, giving ties their average rank.
+	type obs struct {
+		v float64
+		x bool
+	}
+	all := make([]obs, 0, n1+n2)
+	for _, v := range xs {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range ys {
+		all = append(all, obs{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+	var r1, tieTerm float64
+	ties := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // ranks i+1 to j, averaged
+		for k := i; k < j; k++ {
+			if all[k].x {
+				r1 += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties = true
+			tieTerm += t*t*t - t
+		}
+		i = j
+	}
+	u1 := r1 - float64(n1*(n1+1))/2
+	u := math.Min(u1, float64(n1*n2)-u1)
+
+	if !ties && n1*n2 <= 2500 {
+		// P(U <= u) doubled for a two sided test.
+		p := 2 * uCDF(int(u), n1, n2)
+		return math.Min(p, 1)
+	}
+
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	sd := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - tieTerm/(n*(n-1))))
+	if sd == 0 {
+		return 1
+	}
+	z := (math.Abs(u-mean) - 0.5) / sd // with continuity correction
+	if z < 0 {
+		z = 0
+	}
+	return math.Min(math.Erfc(z/math.Sqrt2), 1)
+}
+
+// uCDF returns P(U <= u) for samples of size n1 and n2 without ties. The
+// number of arrangements giving each value of U is counted with the
+// recurrence f(n1, n2, u) = f(n1-1, n2, u-n2) + f(n1, n2-1, u).
+func uCDF(u, n1, n2 int) float64 {
+	maxU := n1 * n2
+	// f[j][v] is the count for samples of size i and j, built up over i.
+	f := make([][]float64, n2+1)
+	for j := range f {
+		f[j] = make([]float64, maxU+1)
+		f[j][0] = 1 // i == 0
+	}
+	for i := 1; i <= n1; i++ {
+		g := make([][]float64, n2+1)
+		g[0] = make([]float64, maxU+1)
+		g[0][0] = 1
+		for j := 1; j <= n2; j++ {
+			g[j] = make([]float64, maxU+1)
+			for v := 0; v <= i*j; v++ {
+				g[j][v] = g[j-1][v]
+				if v >= j {
+					g[j][v] += f[j][v-j]
+				}
+			}
+		}
+		f = g
+	}
+	var count, total float64
+	for v, c := range f[n2] {
+		total += c
+		if v <= u {
+			count += c
+		}
+	}
+	return count / total
+}
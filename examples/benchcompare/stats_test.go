@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := map[string]struct {
+		xs, ys []float64
+		want   float64
+	}{
+		// complete separation, exact p = 2/C(10, 5).
+		"separated": {
+			xs:   []float64{1, 2, 3, 4, 5},
+			ys:   []float64{6, 7, 8, 9, 10},
+			want: 2.0 / 252,
+		},
+		// scipy.stats.mannwhitneyu(x, y, method="exact").
+		"overlapping": {
+			xs:   []float64{19, 22, 16, 29, 24},
+			ys:   []float64{20, 11, 17, 12},
+			want: 0.1111111,
+		},
+		"identical": {
+			xs:   []float64{1, 2, 3},
+			ys:   []float64{1, 2, 3},
+			want: 1,
+		},
+		// ties, scipy.stats.mannwhitneyu(x, y, method="asymptotic").
+		"ties": {
+			xs:   []float64{1, 2, 2, 3, 3, 3, 4},
+			ys:   []float64{3, 4, 4, 5, 5, 6, 6},
+			want: 0.0075909,
+		},
+		"empty": {
+			xs:   nil,
+			ys:   []float64{1},
+			want: 1,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := mannWhitneyU(tc.xs, tc.ys); math.Abs(got-tc.want) > 1e-6 {
+				t.Fatalf("want p=%.7f, got %.7f", tc.want, got)
+			}
+			if got := mannWhitneyU(tc.ys, tc.xs); math.Abs(got-tc.want) > 1e-6 {
+				t.Fatalf("swapped: want p=%.7f, got %.7f", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUCDF(t *testing.T) {
+	// the distribution of U sums to 1 and is symmetric about n1*n2/2.
+	for _, n := range [][2]int{{1, 1}, {3, 4}, {5, 5}, {10, 7}} {
+		n1, n2 := n[0], n[1]
+		if got := uCDF(n1*n2, n1, n2); math.Abs(got-1) > 1e-12 {
+			t.Errorf("uCDF(%d, %d, %d): want 1, got %v", n1*n2, n1, n2, got)
+		}
+		for u := 0; u < n1*n2; u++ {
+			lower := uCDF(u, n1, n2)
+			upper := 1 - uCDF(n1*n2-u-1, n1, n2)
+			if math.Abs(lower-upper) > 1e-12 {
+				t.Errorf("n=%d+%d: P(U<=%d)=%v, P(U>=%d)=%v", n1, n2, u, lower, n1*n2-u, upper)
+			}
+		}
+	}
+}
+
+func TestMedianCI(t *testing.T) {
+	xs := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	lo, hi, ok := medianCI(xs, 0.95)
+	if lo != 2 || hi != 9 || !ok {
+		t.Fatalf("n=10: want [2, 9] true, got [%v, %v] %v", lo, hi, ok)
+	}
+	lo, hi, ok = medianCI(xs[:5], 0.95)
+	if lo != 1 || hi != 5 || ok {
+		t.Fatalf("n=5: want [1, 5] false, got [%v, %v] %v", lo, hi, ok)
+	}
+	if m := median(xs); m != 5.5 {
+		t.Fatalf("median: want 5.5, got %v", m)
+	}
+}
@@ -1,18 +1,14 @@
 package fib
 
-import "testing"
+import (
+	"testing"
 
-func Fib(n int) int {
-	a, b := 0, 1
-	for i := 0; i < n; i++ {
-		a, b = b, a+b
-	}
-	return a
-}
+	"github.com/grafana/high-performance-go-workshop/examples/fib"
+)
 
 // tag::wrong[]
 func BenchmarkFibWrong(b *testing.B) {
-	Fib(b.N)
+	fib.Iterative(b.N)
 }
 
 // end::wrong[]
@@ -20,7 +16,7 @@ func BenchmarkFibWrong(b *testing.B) {
 // tag::wrong2[]
 func BenchmarkFibWrong2(b *testing.B) {
 	for n := 0; n < b.N; n++ {
-		Fib(n)
+		fib.Iterative(n)
 	}
 }
 
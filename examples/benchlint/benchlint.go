@@ -0,0 +1,510 @@
+// Package benchlint defines an analyzer which reports common mistakes in
+// benchmarks, the ones the benchmarking chapter warns about.
+//
+// It reports
+//
+//   - b.N passed as an argument, as in Fib(b.N), so the work done per
+//     iteration changes with b.N; helpers which run a function or
+//     benchmark that many times are allowed,
+//   - the b.N loop counter passed directly as an argument, as in Fib(n),
+//     for the same reason,
+//   - results of side effect free calls which are discarded, rather than
+//     assigned to a sink, so the compiler may eliminate the call,
+//   - b.StopTimer and b.StartTimer called inside the b.N loop, which is
+//     very slow and skews the result,
+//   - expensive setup before the b.N loop without a following call to
+//     b.ResetTimer: a loop, a large allocation, a call to a function in the
+//     package which contains either, or a call to a function known to be
+//     slow, such as os.ReadFile.
+package benchlint
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "benchlint",
+	Doc:      "report common mistakes in benchmarks",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	decls := make(map[*types.Func]*ast.FuncDecl)
+	inspect.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(n ast.Node) {
+		fd := n.(*ast.FuncDecl)
+		if fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func); ok && fd.Body != nil {
+			decls[fn] = fd
+		}
+	})
+	filter := []ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}
+	inspect.Preorder(filter, func(n ast.Node) {
+		var typ *ast.FuncType
+		var body *ast.BlockStmt
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			typ, body = n.Type, n.Body
+		case *ast.FuncLit:
+			typ, body = n.Type, n.Body
+		}
+		if body == nil || !hasBenchmarkParam(pass, typ) {
+			return
+		}
+		c := checker{pass: pass, decls: decls}
+		c.block(body)
+	})
+	return nil, nil
+}
+
+// hasBenchmarkParam reports whether fn takes a *testing.B, so benchmark
+// helpers and the functions passed to b.Run are checked too.
+func hasBenchmarkParam(pass *analysis.Pass, fn *ast.FuncType) bool {
+	for _, field := range fn.Params.List {
+		if isTestingB(pass.TypesInfo.TypeOf(field.Type)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTestingB(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "testing" && obj.Name() == "B"
+}
+
+type checker struct {
+	pass  *analysis.Pass
+	decls map[*types.Func]*ast.FuncDecl // the functions declared in the package
+}
+
+// block checks the statements of a benchmark function, looking for b.N
+// loops. Nested function literals are skipped, they are checked on their
+// own if they take a *testing.B.
+func (c *checker) block(body *ast.BlockStmt) {
+	resetAt := -1 // index of the last statement which resets the timer
+	for i, stmt := range body.List {
+		if c.callsMethod(stmt, "ResetTimer", "StartTimer") {
+			resetAt = i
+		}
+		loop, counter := c.benchmarkLoop(stmt)
+		if loop == nil {
+			continue
+		}
+		if setup := c.expensiveSetup(body.List[resetAt+1 : i]); setup != nil {
+			c.pass.Reportf(setup.Pos(), "expensive setup is included in the benchmark time, call b.ResetTimer before the loop")
+		}
+		c.loopBody(loop.Body, counter)
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ForStmt:
+			if n.Cond != nil {
+				// the loop condition is the one legitimate use of b.N.
+				ast.Inspect(n.Init, c.bN)
+				ast.Inspect(n.Post, c.bN)
+				ast.Inspect(n.Body, c.bN)
+				return false
+			}
+		case *ast.CallExpr:
+			c.bN(n)
+		}
+		return true
+	})
+}
+
+// bN reports calls which pass b.N as an argument. Calls through function
+// values are not reported, they are usually the benchmark's own loop
+// passed in as a parameter, nor are calls to helpers which run b.N
+// iterations.
+func (c *checker) bN(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.FuncLit:
+		return false
+	case *ast.CallExpr:
+		if c.isBuiltin(n.Fun, "make") {
+			// preallocating b.N inputs is fine.
+			return true
+		}
+		fn := c.callee(n.Fun)
+		if fn == nil {
+			return true
+		}
+		for i, arg := range n.Args {
+			if c.isBN(arg) && !c.iterationCount(fn, i) {
+				c.pass.Reportf(arg.Pos(), "b.N passed as an argument, the work done per iteration will change with b.N")
+			}
+		}
+	}
+	return true
+}
+
+// iterationCount reports whether the i'th parameter of fn is an iteration
+// count: fn is declared in the package, takes a function or a *testing.B
+// to run, and uses the parameter as the bound of a loop.
+func (c *checker) iterationCount(fn *types.Func, i int) bool {
+	fd := c.decls[fn]
+	if fd == nil || !takesCode(fn.Type().(*types.Signature)) {
+		return false
+	}
+	var param types.Object
+	for _, field := range fd.Type.Params.List {
+		for _, name := range field.Names {
+			if i == 0 {
+				param = c.pass.TypesInfo.Defs[name]
+			}
+			i--
+		}
+	}
+	if param == nil {
+		return false
+	}
+	isParam := func(e ast.Expr) bool {
+		id, ok := ast.Unparen(e).(*ast.Ident)
+		return ok && c.pass.TypesInfo.Uses[id] == param
+	}
+	found := false
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ForStmt:
+			if cond, ok := n.Cond.(*ast.BinaryExpr); ok && (isParam(cond.X) || isParam(cond.Y)) {
+				found = true
+			}
+		case *ast.RangeStmt:
+			if isParam(n.X) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// takesCode reports whether sig has a parameter which is a function or a
+// *testing.B.
+func takesCode(sig *types.Signature) bool {
+	for i := 0; i < sig.Params().Len(); i++ {
+		t := sig.Params().At(i).Type()
+		if _, ok := t.Underlying().(*types.Signature); ok || isTestingB(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// benchmarkLoop returns the body of stmt if it is a b.N loop, along with
+// the loop counter if there is one.
+func (c *checker) benchmarkLoop(stmt ast.Stmt) (*ast.ForStmt, types.Object) {
+	loop, ok := stmt.(*ast.ForStmt)
+	if !ok {
+		return nil, nil
+	}
+	cond, ok := loop.Cond.(*ast.BinaryExpr)
+	if !ok {
+		return nil, nil
+	}
+	var counter ast.Expr
+	switch {
+	case cond.Op == token.LSS && c.isBN(cond.Y):
+		counter = cond.X
+	case cond.Op == token.GTR && c.isBN(cond.X):
+		counter = cond.Y
+	default:
+		return nil, nil
+	}
+	if id, ok := counter.(*ast.Ident); ok {
+		return loop, c.pass.TypesInfo.ObjectOf(id)
+	}
+	return loop, nil
+}
+
+// isBN reports whether e is b.N, for some b of type *testing.B.
+func (c *checker) isBN(e ast.Expr) bool {
+	sel, ok := ast.Unparen(e).(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "N" && isTestingB(c.pass.TypesInfo.TypeOf(sel.X))
+}
+
+// loopBody checks the body of a b.N loop.
+func (c *checker) loopBody(body *ast.BlockStmt, counter types.Object) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ExprStmt:
+			if call, ok := n.X.(*ast.CallExpr); ok && c.discardable(call) {
+				c.pass.Reportf(call.Pos(), "result of %s is not used, the call may be optimised away; assign it to a package level sink", render(call.Fun))
+			}
+		case *ast.AssignStmt:
+			if len(n.Rhs) == 1 && allBlank(n.Lhs) {
+				if call, ok := n.Rhs[0].(*ast.CallExpr); ok && c.discardable(call) {
+					c.pass.Reportf(call.Pos(), "result of %s is assigned to _, the call may be optimised away; assign it to a package level sink", render(call.Fun))
+				}
+			}
+		case *ast.CallExpr:
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok && isTestingB(c.pass.TypesInfo.TypeOf(sel.X)) {
+				switch sel.Sel.Name {
+				case "StopTimer", "StartTimer":
+					c.pass.Reportf(n.Pos(), "b.%s called inside the benchmark loop, this is very slow and skews the result", sel.Sel.Name)
+				}
+			}
+			if fn := c.callee(n.Fun); counter != nil && fn != nil && c.underTest(fn) {
+				for _, arg := range n.Args {
+					if id, ok := arg.(*ast.Ident); ok && c.pass.TypesInfo.ObjectOf(id) == counter {
+						c.pass.Reportf(arg.Pos(), "loop counter %s passed as an argument, the work done per iteration will change with b.N", id.Name)
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+// discardable reports whether call is to code under test which returns a result and, because it has no pointer receiver and
+// its arguments hold no pointers, is likely free of side effects. If such
+// a call is inlined and its result is unused, the compiler is free to
+// remove it.
+func (c *checker) discardable(call *ast.CallExpr) bool {
+	fn := c.callee(call.Fun)
+	if fn == nil || !c.underTest(fn) {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Results().Len() == 0 {
+		return false
+	}
+	if recv := sig.Recv(); recv != nil && hasPointers(recv.Type()) {
+		return false
+	}
+	for _, arg := range call.Args {
+		if hasPointers(c.pass.TypesInfo.TypeOf(arg)) {
+			return false
+		}
+	}
+	return true
+}
+
+// underTest reports whether fn is code under test: it is in the package
+// being checked, or in another package outside the standard library, as
+// when an external test package or a separate benchmark package imports
+// it.
+func (c *checker) underTest(fn *types.Func) bool {
+	if fn.Pkg() == nil {
+		return false
+	}
+	if fn.Pkg() == c.pass.Pkg {
+		return true
+	}
+	// Standard library import paths have no dot in their first element.
+	first, _, _ := strings.Cut(fn.Pkg().Path(), "/")
+	return strings.Contains(first, ".")
+}
+
+// callee returns the function or method named by fun, or nil if fun is
+// some other expression.
+func (c *checker) callee(fun ast.Expr) *types.Func {
+	var id *ast.Ident
+	switch fun := ast.Unparen(fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	fn, _ := c.pass.TypesInfo.ObjectOf(id).(*types.Func)
+	return fn
+}
+
+func (c *checker) isBuiltin(fun ast.Expr, name string) bool {
+	id, ok := ast.Unparen(fun).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	b, ok := c.pass.TypesInfo.ObjectOf(id).(*types.Builtin)
+	return ok && b.Name() == name
+}
+
+// largeAlloc is the number of elements above which make is expensive.
+const largeAlloc = 1 << 16
+
+// knownExpensive are functions outside the package which are expensive
+// enough to be worth excluding from the benchmark time.
+var knownExpensive = map[string]bool{
+	"bytes.Repeat":   true,
+	"strings.Repeat": true,
+	"io.ReadAll":     true,
+	"os.ReadFile":    true,
+}
+
+// expensiveSetup returns the first node in stmts which could be expensive:
+// a loop, a call to make with a large constant size, a call to a function
+// in the package which contains either, or a call to one of knownExpensive.
+func (c *checker) expensiveSetup(stmts []ast.Stmt) ast.Node {
+	for _, stmt := range stmts {
+		if _, ok := stmt.(*ast.DeferStmt); ok {
+			continue
+		}
+		if n := c.expensive(stmt, true); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// expensive returns the first loop or large allocation in n. If calls is
+// true, n is in the benchmark and calls to functions in the package are
+// followed, one level deep. In those functions a loop must range over
+// something or do work, call or allocate, in its body to count, so that
+// loops such as rounding up to a power of two are not reported.
+func (c *checker) expensive(n ast.Node, calls bool) ast.Node {
+	var found ast.Node
+	ast.Inspect(n, func(n ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.RangeStmt:
+			found = n
+		case *ast.ForStmt:
+			if calls || c.doesWork(n.Body) {
+				found = n
+			}
+		case *ast.CallExpr:
+			if c.isLargeMake(n) {
+				found = n
+				break
+			}
+			fn := c.callee(n.Fun)
+			if fn == nil {
+				break
+			}
+			if fd := c.decls[fn]; fd != nil && calls && c.expensive(fd.Body, false) != nil {
+				found = n
+			}
+			if fn.Pkg() != nil && knownExpensive[fn.Pkg().Path()+"."+fn.Name()] {
+				found = n
+			}
+		}
+		return found == nil
+	})
+	return found
+}
+
+// doesWork reports whether body calls a function or allocates.
+func (c *checker) doesWork(body *ast.BlockStmt) bool {
+	work := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CompositeLit:
+			work = true
+		case *ast.CallExpr:
+			if !c.pass.TypesInfo.Types[n.Fun].IsType() {
+				work = true
+			}
+		}
+		return !work
+	})
+	return work
+}
+
+// isLargeMake reports whether call is to make with a constant length or
+// capacity of at least largeAlloc.
+func (c *checker) isLargeMake(call *ast.CallExpr) bool {
+	if !c.isBuiltin(call.Fun, "make") {
+		return false
+	}
+	for _, arg := range call.Args[1:] {
+		if v := c.pass.TypesInfo.Types[arg].Value; v != nil && v.Kind() == constant.Int {
+			if n, ok := constant.Int64Val(v); !ok || n >= largeAlloc {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callsMethod reports whether stmt is a call to one of the named methods
+// of *testing.B.
+func (c *checker) callsMethod(stmt ast.Stmt, names ...string) bool {
+	es, ok := stmt.(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	call, ok := es.X.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !isTestingB(c.pass.TypesInfo.TypeOf(sel.X)) {
+		return false
+	}
+	for _, name := range names {
+		if sel.Sel.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPointers reports whether values of type t contain pointers, and so
+// might be used to cause side effects.
+func hasPointers(t types.Type) bool {
+	switch t := t.Underlying().(type) {
+	case *types.Basic:
+		return t.Kind() == types.UnsafePointer
+	case *types.Array:
+		return hasPointers(t.Elem())
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if hasPointers(t.Field(i).Type()) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func allBlank(exprs []ast.Expr) bool {
+	for _, e := range exprs {
+		if id, ok := e.(*ast.Ident); !ok || id.Name != "_" {
+			return false
+		}
+	}
+	return true
+}
+
+// render returns the source form of a function expression, for messages.
+func render(fun ast.Expr) string {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return render(fun.X) + "." + fun.Sel.Name
+	default:
+		return "the call"
+	}
+}
@@ -0,0 +1,17 @@
+package benchlint
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// The fixtures in testdata are the examples from examples/benchfib,
+// examples/benchstartstop, 02-benchmarking/examples/reset.go and
+// examples/popcnt. example.com/benchfib imports the code under test, as
+// examples/benchfib does. helpers holds benchmarks like those in
+// examples/benchtimer, examples/range, examples/falseshare and
+// examples/counter, which are correct and must not be reported.
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "benchfib", "benchstartstop", "reset", "good", "helpers", "example.com/benchfib")
+}
@@ -0,0 +1,12 @@
+// benchlint reports common mistakes in benchmarks.
+//
+//	go run ./examples/benchlint/cmd/benchlint ./examples/...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/grafana/high-performance-go-workshop/examples/benchlint"
+)
+
+func main() { singlechecker.Main(benchlint.Analyzer) }
@@ -0,0 +1,37 @@
+package fib
+
+import "testing"
+
+func Fib(n int) int {
+	a, b := 0, 1
+	for i := 0; i < n; i++ {
+		a, b = b, a+b
+	}
+	return a
+}
+
+func BenchmarkFibWrong(b *testing.B) {
+	Fib(b.N) // want `b.N passed as an argument`
+}
+
+func BenchmarkFibWrong2(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		Fib(n) // want `result of Fib is not used` `loop counter n passed as an argument`
+	}
+}
+
+func BenchmarkFibBlank(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		_ = Fib(20) // want `result of Fib is assigned to _`
+	}
+}
+
+var Result int
+
+func BenchmarkFib20(b *testing.B) {
+	var r int
+	for n := 0; n < b.N; n++ {
+		r = Fib(20)
+	}
+	Result = r
+}
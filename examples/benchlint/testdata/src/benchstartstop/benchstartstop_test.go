@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+var Result int
+
+func BenchmarkStartStop(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		b.StopTimer() // want `b.StopTimer called inside the benchmark loop`
+		Result++
+		b.StartTimer() // want `b.StartTimer called inside the benchmark loop`
+		Result += int(time.Now().Unix())
+	}
+}
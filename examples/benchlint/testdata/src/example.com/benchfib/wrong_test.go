@@ -0,0 +1,24 @@
+package benchfib
+
+import (
+	"strconv"
+	"testing"
+
+	"example.com/fib"
+)
+
+func BenchmarkFibWrong(b *testing.B) {
+	fib.Iterative(b.N) // want `b.N passed as an argument`
+}
+
+func BenchmarkFibWrong2(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		fib.Iterative(n) // want `result of fib.Iterative is not used` `loop counter n passed as an argument`
+	}
+}
+
+func BenchmarkItoa(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		strconv.Itoa(n) // the standard library is not the code under test
+	}
+}
@@ -0,0 +1,9 @@
+package fib
+
+func Iterative(n int) int {
+	a, b := 0, 1
+	for i := 0; i < n; i++ {
+		a, b = b, a+b
+	}
+	return a
+}
@@ -0,0 +1,72 @@
+package good
+
+import (
+	"bytes"
+	"testing"
+)
+
+const m1 = 0x5555555555555555
+const m2 = 0x3333333333333333
+const m4 = 0x0f0f0f0f0f0f0f0f
+const h01 = 0x0101010101010101
+
+func popcnt(x uint64) uint64 {
+	x -= (x >> 1) & m1
+	x = (x & m2) + ((x >> 2) & m2)
+	x = (x + (x >> 4)) & m4
+	return (x * h01) >> 56
+}
+
+var Result uint64
+
+func BenchmarkPopcnt(b *testing.B) {
+	var r uint64
+	for i := 0; i < b.N; i++ {
+		r = popcnt(uint64(i))
+	}
+	Result = r
+}
+
+type counter uint64
+
+func (c *counter) inc() uint64 {
+	*c++
+	return uint64(*c)
+}
+
+func BenchmarkInc(b *testing.B) {
+	var c counter
+	for i := 0; i < b.N; i++ {
+		c.inc() // pointer receiver, has side effects
+	}
+}
+
+func BenchmarkBytes(b *testing.B) {
+	x := bytes.Repeat([]byte{'a'}, 1<<20)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if len(x) == 0 {
+			b.Fatal("empty")
+		}
+	}
+}
+
+func BenchmarkInputs(b *testing.B) {
+	inputs := make([]uint64, b.N)
+	var r uint64
+	for i := 0; i < b.N; i++ {
+		r += popcnt(inputs[i])
+	}
+	Result = r
+}
+
+func BenchmarkSub(b *testing.B) {
+	b.Run("popcnt", func(b *testing.B) {
+		var r uint64
+		for i := 0; i < b.N; i++ {
+			r = popcnt(uint64(i))
+		}
+		Result = r
+	})
+}
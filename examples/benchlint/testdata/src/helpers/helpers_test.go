@@ -0,0 +1,143 @@
+package helpers
+
+import (
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Batch and batch are from examples/benchtimer: batch takes an iteration
+// count, not an input size.
+func Batch(b *testing.B, k int, setup func(n int), fn func(i int)) {
+	batch(b.N, k, b.StopTimer, b.StartTimer, setup, fn)
+}
+
+func batch(iterations, k int, stop, start func(), setup func(n int), fn func(i int)) {
+	for done := 0; done < iterations; done += k {
+		n := min(k, iterations-done)
+		stop()
+		setup(n)
+		start()
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+	}
+}
+
+// run calls fn n times.
+func run(n int, fn func()) {
+	for range n {
+		fn()
+	}
+}
+
+func BenchmarkRun(b *testing.B) {
+	run(b.N, func() {})
+}
+
+// pattern is from examples/range: access makes n accesses to buf.
+type pattern struct {
+	access func(buf []uint64, n int) uint64
+}
+
+var Result int
+
+func BenchmarkAccess(b *testing.B) {
+	p := pattern{access: func(buf []uint64, n int) uint64 { return 0 }}
+	buf := make([]uint64, 1024)
+	b.SetBytes(8)
+	Result = int(p.access(buf, b.N))
+}
+
+// Counters is from examples/falseshare: its constructor allocates a few
+// cache lines, which is not worth excluding.
+type Counters struct {
+	slots []uint64
+}
+
+func New(n int) *Counters {
+	return &Counters{slots: make([]uint64, (n+1)*8)}
+}
+
+func (c *Counters) Sum() uint64 {
+	var s uint64
+	for _, v := range c.slots {
+		s += v
+	}
+	return s
+}
+
+var Sink uint64
+
+func BenchmarkSum(b *testing.B) {
+	c := New(4)
+	var r uint64
+	for i := 0; i < b.N; i++ {
+		r = c.Sum()
+	}
+	Sink = r
+}
+
+// newSharded is from examples/counter: its loop is cheap.
+func newSharded(procs int) []uint64 {
+	n := 1
+	for n < procs {
+		n <<= 1
+	}
+	return make([]uint64, n*8)
+}
+
+func BenchmarkSharded(b *testing.B) {
+	s := newSharded(4)
+	for i := 0; i < b.N; i++ {
+		s[0]++
+	}
+}
+
+func BenchmarkSort(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	s := make([]int, 100)
+	for n := 0; n < b.N; n++ {
+		for i := range s {
+			s[i] = r.Int()
+		}
+		sort.Ints(s)
+	}
+}
+
+func fill(s []int) {
+	for i := range s {
+		s[i] = i
+	}
+}
+
+func BenchmarkFill(b *testing.B) {
+	s := make([]int, 100)
+	fill(s) // want `expensive setup is included in the benchmark time`
+	for n := 0; n < b.N; n++ {
+		sort.Ints(s)
+	}
+}
+
+func BenchmarkLoop(b *testing.B) {
+	var s []int
+	for i := 0; i < 100; i++ { // want `expensive setup is included in the benchmark time`
+		s = append(s, i)
+	}
+	for n := 0; n < b.N; n++ {
+		sort.Ints(s)
+	}
+}
+
+func BenchmarkReadFile(b *testing.B) {
+	data, _ := os.ReadFile("testdata/input") // want `expensive setup is included in the benchmark time`
+	for n := 0; n < b.N; n++ {
+		Result = len(data)
+	}
+}
+
+func BenchmarkRepeat(b *testing.B) {
+	Result = len(strings.Repeat("x", b.N)) // want `b.N passed as an argument`
+}
@@ -0,0 +1,32 @@
+package q
+
+import "testing"
+
+func boringAndExpensiveSetup() []int { return make([]int, 1<<20) }
+
+func complicatedSetup() {}
+
+var Result int
+
+func BenchmarkExpensive(b *testing.B) {
+	boringAndExpensiveSetup()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+	}
+}
+
+func BenchmarkExpensiveNoReset(b *testing.B) {
+	s := boringAndExpensiveSetup() // want `expensive setup is included in the benchmark time`
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		Result += len(s)
+	}
+}
+
+func BenchmarkComplicated(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		b.StopTimer() // want `b.StopTimer called inside the benchmark loop`
+		complicatedSetup()
+		b.StartTimer() // want `b.StartTimer called inside the benchmark loop`
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// sortedTable holds keys and values in slices sorted by key, and looks up
+// keys with a binary search.
+type sortedTable struct {
+	keys, values []string
+}
+
+func newSortedTable(m map[string]string) *sortedTable {
+	t := &sortedTable{keys: make([]string, 0, len(m))}
+	for k := range m {
+		t.keys = append(t.keys, k)
+	}
+	sort.Strings(t.keys)
+	for _, k := range t.keys {
+		t.values = append(t.values, m[k])
+	}
+	return t
+}
+
+func (t *sortedTable) get(key string) (string, bool) {
+	i, ok := slices.BinarySearch(t.keys, key)
+	if !ok {
+		return "", false
+	}
+	return t.values[i], true
+}
+
+// perfectTable is a hash table built for a fixed set of keys, using the
+// hash and displace algorithm, so that no two keys share a slot and a
+// lookup needs no probing.
+//
+// Keys are first hashed into buckets of about four. Then, largest bucket
+// first, a seed is searched for which hashes every key in the bucket to an
+// empty slot. A lookup hashes the key once to find its bucket's seed, and
+// again with the seed to find its slot, then compares the key in the slot,
+// in case it was not one of the set.
+type perfectTable struct {
+	seeds   []uint64 // per bucket
+	mask    uint64   // slots - 1
+	entries []perfectEntry
+}
+
+type perfectEntry struct {
+	key, value string
+	full       bool
+}
+
+func newPerfectTable(m map[string]string) *perfectTable {
+	slots := 1
+	for slots < len(m) {
+		slots <<= 1
+	}
+	buckets := make([][]string, max(len(m)/4, 1))
+	for k := range m {
+		b := perfectHash(0, k) % uint64(len(buckets))
+		buckets[b] = append(buckets[b], k)
+	}
+	order := make([]int, len(buckets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return len(buckets[order[i]]) > len(buckets[order[j]]) })
+
+	t := &perfectTable{
+		seeds:   make([]uint64, len(buckets)),
+		mask:    uint64(slots - 1),
+		entries: make([]perfectEntry, slots),
+	}
+	used := make([]bool, slots)
+	var tried []uint64
+	for _, b := range order {
+		if len(buckets[b]) == 0 {
+			continue
+		}
+	search:
+		for seed := uint64(1); ; seed++ {
+			if seed == 1<<24 {
+				panic("perfectTable: no seed found")
+			}
+			tried = tried[:0]
+			for _, k := range buckets[b] {
+				s := perfectHash(seed, k) & t.mask
+				if used[s] || slices.Contains(tried, s) {
+					continue search
+				}
+				tried = append(tried, s)
+			}
+			for i, k := range buckets[b] {
+				used[tried[i]] = true
+				t.entries[tried[i]] = perfectEntry{k, m[k], true}
+			}
+			t.seeds[b] = seed
+			break
+		}
+	}
+	return t
+}
+
+func (t *perfectTable) get(key string) (string, bool) {
+	seed := t.seeds[perfectHash(0, key)%uint64(len(t.seeds))]
+	e := &t.entries[perfectHash(seed, key)&t.mask]
+	if !e.full || e.key != key {
+		return "", false
+	}
+	return e.value, true
+}
+
+// perfectHash is FNV-1a, with seed folded into its starting value and
+// its result mixed so that the low bits, used to pick a slot, depend on
+// every byte.
+func perfectHash(seed uint64, s string) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset) ^ seed*0x9e3779b97f4a7c15
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime
+	}
+	h ^= h >> 32
+	h *= 0xd6e8feb86659fd93
+	h ^= h >> 32
+	return h
+}
+
+// lookups are the ways of finding a capital.
+var lookups = []struct {
+	name string
+	new  func(map[string]string) func(string) (string, bool)
+}{
+	{"map", func(m map[string]string) func(string) (string, bool) {
+		return func(k string) (string, bool) { v, ok := m[k]; return v, ok }
+	}},
+	{"sorted", func(m map[string]string) func(string) (string, bool) {
+		return newSortedTable(m).get
+	}},
+	{"perfect", func(m map[string]string) func(string) (string, bool) {
+		return newPerfectTable(m).get
+	}},
+}
+
+func TestLookupsAgree(t *testing.T) {
+	for _, m := range []map[string]string{capitals, synthetic(1), synthetic(1000), synthetic(1 << 16)} {
+		keys := []string{"", "Atlantis", "france", "France ", "Franc", "country-1000000"}
+		for k := range m {
+			keys = append(keys, k)
+		}
+		for _, l := range lookups {
+			get := l.new(m)
+			for _, k := range keys {
+				want, wantOK := m[k]
+				if got, ok := get(k); got != want || ok != wantOK {
+					t.Fatalf("%s, %d keys: get(%q) = %q, %v, want %q, %v", l.name, len(m), k, got, ok, want, wantOK)
+				}
+			}
+		}
+	}
+}
+
+func TestPerfectTableEmpty(t *testing.T) {
+	if v, ok := newPerfectTable(nil).get("France"); v != "" || ok {
+		t.Fatalf("empty table: got %q, %v", v, ok)
+	}
+}
+
+// synthetic returns a map of n countries and their capitals.
+func synthetic(n int) map[string]string {
+	m := make(map[string]string, n)
+	for i := range n {
+		m["country-"+strconv.Itoa(i)] = "capital-" + strconv.Itoa(i)
+	}
+	return m
+}
+
+var sizes = []int{16, 1 << 10, 1 << 16}
+
+// BenchmarkCapitals looks up each of the capitals in turn.
+func BenchmarkCapitals(b *testing.B) {
+	keys := make([]string, 0, len(capitals))
+	for k := range capitals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, l := range lookups {
+		b.Run(l.name, func(b *testing.B) {
+			get := l.new(capitals)
+			b.ResetTimer()
+			var r string
+			for n := 0; n < b.N; n++ {
+				r, _ = get(keys[n%len(keys)])
+			}
+			sink = r
+		})
+	}
+}
+
+// BenchmarkStringLookup compares the lookups as the number of keys grows.
+func BenchmarkStringLookup(b *testing.B) {
+	for _, size := range sizes {
+		m := synthetic(size)
+		keys := lookupKeys(m)
+		for _, l := range lookups {
+			b.Run(l.name+"/"+strconv.Itoa(size), func(b *testing.B) {
+				get := l.new(m)
+				b.ResetTimer()
+				var r string
+				for n := 0; n < b.N; n++ {
+					r, _ = get(keys[n&(len(keys)-1)])
+				}
+				sink = r
+			})
+		}
+	}
+}
+
+// lookupKeys returns 1024 of the keys of m, in random order, repeating
+// them if there are fewer.
+func lookupKeys[K comparable, V any](m map[K]V) []K {
+	keys := make([]K, 0, 1024)
+	for len(keys) < cap(keys) {
+		for k := range m {
+			if len(keys) == cap(keys) {
+				break
+			}
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+type point struct {
+	x, y int32
+}
+
+var (
+	intSink   int
+	pointSink point
+)
+
+// BenchmarkKeyTypes compares map lookups with different types of key.
+// The bytes case converts a []byte to a string in the index expression,
+// which, as BenchmarkMapLookup shows, does not allocate.
+func BenchmarkKeyTypes(b *testing.B) {
+	for _, size := range sizes {
+		name := "/" + strconv.Itoa(size)
+
+		strs := synthetic(size)
+		strKeys := lookupKeys(strs)
+		b.Run("string"+name, func(b *testing.B) {
+			var r string
+			for n := 0; n < b.N; n++ {
+				r = strs[strKeys[n&(len(strKeys)-1)]]
+			}
+			sink = r
+		})
+
+		byteKeys := make([][]byte, len(strKeys))
+		for i, k := range strKeys {
+			byteKeys[i] = []byte(k)
+		}
+		b.Run("bytes"+name, func(b *testing.B) {
+			b.ReportAllocs()
+			var r string
+			for n := 0; n < b.N; n++ {
+				r = strs[string(byteKeys[n&(len(byteKeys)-1)])]
+			}
+			sink = r
+		})
+
+		ints := make(map[int]int, size)
+		for i := range size {
+			ints[i*7919] = i
+		}
+		intKeys := lookupKeys(ints)
+		b.Run("int"+name, func(b *testing.B) {
+			var r int
+			for n := 0; n < b.N; n++ {
+				r = ints[intKeys[n&(len(intKeys)-1)]]
+			}
+			intSink = r
+		})
+
+		points := make(map[point]point, size)
+		for i := range size {
+			p := point{int32(i), int32(-i)}
+			points[p] = p
+		}
+		pointKeys := lookupKeys(points)
+		b.Run("struct"+name, func(b *testing.B) {
+			var r point
+			for n := 0; n < b.N; n++ {
+				r = points[pointKeys[n&(len(pointKeys)-1)]]
+			}
+			pointSink = r
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Package benchtimer measures the cost of the benchmark timer and provides
+// a way to exclude per iteration setup from a benchmark without calling
+// b.StopTimer and b.StartTimer on every iteration.
+package benchtimer
+
+import (
+	"testing"
+	"time"
+)
+
+// TimeNow returns the average cost of a call to time.Now on this machine.
+func TimeNow() time.Duration {
+	const calls = 1 << 20
+	var t time.Time
+	start := time.Now()
+	for i := 0; i < calls; i++ {
+		t = time.Now()
+	}
+	return t.Sub(start) / calls
+}
+
+// StopStartTimer returns the average wall clock cost of a b.StopTimer,
+// b.StartTimer pair on this machine. StopTimer and StartTimer read the
+// memory statistics, to report allocations, which stops the world, so the
+// cost is usually far larger than the cost of reading the clock.
+func StopStartTimer() time.Duration {
+	const pairs = 1 << 10
+	var d time.Duration
+	testing.Benchmark(func(b *testing.B) {
+		// b.N is deliberately ignored. The timer is stopped almost all the
+		// time, so the benchmark framework would keep raising b.N, and with
+		// it the run time, towards its limit of a billion iterations.
+		start := time.Now()
+		for i := 0; i < pairs; i++ {
+			b.StopTimer()
+			b.StartTimer()
+		}
+		d = time.Since(start) / pairs
+	})
+	return d
+}
+
+// Batch runs b.N iterations of fn in batches of at most k iterations.
+// Before each batch setup is called, with the timer stopped, with the
+// number of iterations in the batch; fn is then called with i counting
+// from 0 to that number. This lets setup prepare k inputs at a time, so
+// the cost of stopping and starting the timer is paid once every k
+// iterations rather than on every one.
+//
+// Each call to fn is an indirect function call, which adds a nanosecond or
+// two to every iteration.
+func Batch(b *testing.B, k int, setup func(n int), fn func(i int)) {
+	batch(b.N, k, b.StopTimer, b.StartTimer, setup, fn)
+}
+
+func batch(iterations, k int, stop, start func(), setup func(n int), fn func(i int)) {
+	if k < 1 {
+		k = 1
+	}
+	stop()
+	for done := 0; done < iterations; done += k {
+		n := min(k, iterations-done)
+		setup(n)
+		start()
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		stop()
+	}
+	start()
+}
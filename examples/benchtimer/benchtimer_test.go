@@ -0,0 +1,123 @@
+package benchtimer
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestOverheads(t *testing.T) {
+	if testing.Short() {
+		t.Skip("calibration is slow")
+	}
+	now, timer := TimeNow(), StopStartTimer()
+	t.Logf("time.Now: %v, b.StopTimer+b.StartTimer: %v", now, timer)
+	if now <= 0 || timer <= 0 {
+		t.Fatalf("want positive overheads, got %v and %v", now, timer)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		iterations, k int
+		want          []int // batch sizes
+	}{
+		{iterations: 0, k: 10, want: nil},
+		{iterations: 5, k: 0, want: []int{1, 1, 1, 1, 1}},
+		{iterations: 10, k: 3, want: []int{3, 3, 3, 1}},
+		{iterations: 10, k: 10, want: []int{10}},
+		{iterations: 10, k: 100, want: []int{10}},
+	}
+	for _, tt := range tests {
+		var batches []int
+		var calls []int
+		timerOn := true
+		stop := func() { timerOn = false }
+		start := func() { timerOn = true }
+		batch(tt.iterations, tt.k, stop, start, func(n int) {
+			if timerOn {
+				t.Errorf("%d/%d: setup called with the timer running", tt.iterations, tt.k)
+			}
+			batches = append(batches, n)
+		}, func(i int) {
+			if !timerOn {
+				t.Errorf("%d/%d: fn called with the timer stopped", tt.iterations, tt.k)
+			}
+			calls = append(calls, i)
+		})
+		if !slices.Equal(batches, tt.want) {
+			t.Errorf("%d/%d: want batches %v, got %v", tt.iterations, tt.k, tt.want, batches)
+		}
+		var want []int
+		for _, n := range tt.want {
+			for i := 0; i < n; i++ {
+				want = append(want, i)
+			}
+		}
+		if !slices.Equal(calls, want) {
+			t.Errorf("%d/%d: want calls %v, got %v", tt.iterations, tt.k, want, calls)
+		}
+		if !timerOn {
+			t.Errorf("%d/%d: timer left stopped", tt.iterations, tt.k)
+		}
+	}
+}
+
+const size = 100
+
+// complicatedSetup returns a shuffled slice for sort.Ints to sort. Sorting
+// consumes its input, so every iteration needs a fresh one.
+func complicatedSetup(r *rand.Rand) []int {
+	s := make([]int, size)
+	for i := range s {
+		s[i] = r.Int()
+	}
+	return s
+}
+
+// BenchmarkComplicated is BenchmarkComplicated from
+// 02-benchmarking/examples/reset.go.
+func BenchmarkComplicated(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		s := complicatedSetup(r)
+		b.StartTimer()
+		sort.Ints(s)
+	}
+}
+
+// BenchmarkComplicatedBatched prepares 100 inputs at a time.
+func BenchmarkComplicatedBatched(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	inputs := make([][]int, 100)
+	Batch(b, len(inputs), func(n int) {
+		for i := range inputs[:n] {
+			inputs[i] = complicatedSetup(r)
+		}
+	}, func(i int) {
+		sort.Ints(inputs[i])
+	})
+}
+
+// BenchmarkSortWithSetup includes the setup in the measurement, the time
+// of complicatedSetup can be subtracted by comparing with
+// BenchmarkSetupOnly.
+func BenchmarkSortWithSetup(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < b.N; n++ {
+		sort.Ints(complicatedSetup(r))
+	}
+}
+
+var Result []int
+
+func BenchmarkSetupOnly(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	var s []int
+	for n := 0; n < b.N; n++ {
+		s = complicatedSetup(r)
+	}
+	Result = s
+}
@@ -0,0 +1,27 @@
+package bytesize
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	for s, want := range map[string]int64{"4096": 4096, "4KB": 4 << 10, "16mb": 16 << 20, "1GB": 1 << 30, "8589934591GB": 8589934591 << 30} {
+		if got, err := Parse(s); got != want || err != nil {
+			t.Errorf("Parse(%q) = %d, %v, want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "KB", "-1MB", "1TB", "9999999999GB", "8589934592GB", "9223372036854775808"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) succeeded", s)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for n, want := range map[int64]string{4096: "4KB", 4097: "4097", 16 << 20: "16MB", 3 << 30: "3GB", 1536 << 10: "1536KB"} {
+		if got := Format(n); got != want {
+			t.Errorf("Format(%d) = %q, want %q", n, got, want)
+		}
+		if got, err := Parse(want); got != n || err != nil {
+			t.Errorf("Parse(Format(%d)) = %d, %v", n, got, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/grafana/high-performance-go-workshop/examples/falseshare"
+)
+
+func TestShardedCounter(t *testing.T) {
+	for _, padded := range []bool{false, true} {
+		c := newShardedCounter(padded)
+		const goroutines, incs = 8, 10000
+		var wg sync.WaitGroup
+		wg.Add(goroutines)
+		for g := 0; g < goroutines; g++ {
+			go func() {
+				defer wg.Done()
+				shard := c.shard()
+				for i := 0; i < incs; i++ {
+					c.inc(shard)
+				}
+			}()
+		}
+		wg.Wait()
+		if got := c.get(); got != goroutines*incs {
+			t.Fatalf("padded: %v: get: want %d, got %d", padded, goroutines*incs, got)
+		}
+		if got := c.reset(); got != goroutines*incs {
+			t.Fatalf("padded: %v: reset: want %d, got %d", padded, goroutines*incs, got)
+		}
+		if got := c.get(); got != 0 {
+			t.Fatalf("padded: %v: get after reset: want 0, got %d", padded, got)
+		}
+	}
+}
+
+// falseshare.New aligns each slot to its layout, so a padded counter's
+// slots are aligned to the cache line if its layout is the line size.
+func TestShardedCounterAlignment(t *testing.T) {
+	c := newShardedCounter(true)
+	line, _ := falseshare.CacheLineSize()
+	if c.layout != falseshare.Layout(line) {
+		t.Fatalf("slots are %d bytes apart, want a %d byte cache line", c.layout, line)
+	}
+}
+
+// compare with BenchmarkInc in examples/mutex, which increments a counter
+// guarded by a sync.Mutex.
+func BenchmarkAtomicInc(b *testing.B) {
+	var c counter
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.inc()
+		}
+	})
+}
+
+// compare with -cpu=1,2,4,8 to see the packed slots degrade as the number
+// of Ps writing to the same cache line grows.
+func BenchmarkShardedInc(b *testing.B) {
+	b.Run("packed", func(b *testing.B) {
+		benchmarkShardedInc(b, newShardedCounter(false))
+	})
+	b.Run("padded", func(b *testing.B) {
+		benchmarkShardedInc(b, newShardedCounter(true))
+	})
+}
+
+func benchmarkShardedInc(b *testing.B, c *shardedCounter) {
+	b.RunParallel(func(pb *testing.PB) {
+		shard := c.shard()
+		for pb.Next() {
+			c.inc(shard)
+		}
+	})
+}
+
+var Result uint64
+
+func BenchmarkShardedGet(b *testing.B) {
+	c := newShardedCounter(true)
+	var r uint64
+	for n := 0; n < b.N; n++ {
+		r = c.get()
+	}
+	Result = r
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"github.com/grafana/high-performance-go-workshop/examples/falseshare"
+)
+
+// shardedCounter spreads increments across several slots so that goroutines
+// running on different Ps do not contend on the same cache line. Reads sum
+// every slot, so they are slower than counter.get.
+type shardedCounter struct {
+	slots  *falseshare.Counters
+	layout falseshare.Layout
+	mask   uint64
+	next   uint64 // the next slot handed out by shard
+}
+
+// newShardedCounter returns a counter with a slot per P, rounded up to a
+// power of two. If padded is true each slot occupies its own cache line,
+// otherwise slots are packed next to each other and suffer false sharing.
+func newShardedCounter(padded bool) *shardedCounter {
+	n := 1
+	for n < runtime.GOMAXPROCS(0) {
+		n <<= 1
+	}
+	layout := falseshare.Packed
+	if padded {
+		// the error is ignored, CacheLineSize returns a sensible
+		// default when the size is unknown.
+		line, _ := falseshare.CacheLineSize()
+		layout = falseshare.Layout(line)
+	}
+	return &shardedCounter{
+		slots:  falseshare.New(n, layout),
+		layout: layout,
+		mask:   uint64(n - 1),
+	}
+}
+
+// shard returns a slot for the calling goroutine to increment. Slots are
+// handed out round robin, so goroutines which take one each and keep it
+// mostly write to different cache lines.
+func (c *shardedCounter) shard() uint64 {
+	return (atomic.AddUint64(&c.next, 1) - 1) & c.mask
+}
+
+// inc increments the counter through the slot returned by shard.
+func (c *shardedCounter) inc(shard uint64) {
+	c.slots.Inc(int(shard))
+}
+
+func (c *shardedCounter) get() uint64 {
+	return c.slots.Sum()
+}
+
+// reset zeros the counter and returns its previous value. Increments which
+// race with reset are counted either before or after, never lost.
+func (c *shardedCounter) reset() uint64 {
+	var sum uint64
+	for i := range c.slots.Len() {
+		sum += c.slots.Swap(i, 0)
+	}
+	return sum
+}
@@ -0,0 +1,254 @@
+// deadbench reports benchmark loops whose body has been optimised away.
+//
+// It compiles the test binary of each package with -gcflags=-S and looks,
+// in every Benchmark function and closure, for loops in which every
+// instruction belongs to the for statement itself. Such a loop increments
+// its counter and compares it to b.N, but does nothing else, so the
+// benchmark measures nothing. Only amd64 and arm64 are supported; set
+// GOARCH to check the code generated for either.
+//
+//	go run ./examples/deadbench ./examples/popcnt
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("deadbench: ")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: deadbench [packages]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	pkgs := flag.Args()
+	if len(pkgs) == 0 {
+		pkgs = []string{"."}
+	}
+
+	if arch, err := goarch(); err != nil {
+		log.Fatal(err)
+	} else if !supported[arch] {
+		log.Fatalf("GOARCH=%s is not supported, only amd64 and arm64", arch)
+	}
+
+	found := false
+	for _, pkg := range pkgs {
+		asm, err := compile(pkg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, l := range deadLoops(parse(bytes.NewReader(asm))) {
+			fmt.Printf("%s:%d: %s: loop body optimised away\n", l.file, l.line, l.fn)
+			found = true
+		}
+	}
+	if found {
+		os.Exit(1)
+	}
+}
+
+// supported are the architectures whose branch instructions parseInst
+// recognises.
+var supported = map[string]bool{"amd64": true, "arm64": true}
+
+// goarch returns the architecture go test will compile for.
+func goarch() (string, error) {
+	out, err := exec.Command("go", "env", "GOARCH").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env GOARCH: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// compile builds the test binary for pkg, discarding it, and returns the
+// assembly listing of the package and its tests.
+func compile(pkg string) ([]byte, error) {
+	cmd := exec.Command("go", "test", "-c", "-o", os.DevNull, "-gcflags=-S", pkg)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %v\n%s", pkg, err, stderr.Bytes())
+	}
+	return stderr.Bytes(), nil
+}
+
+// function is the assembly listing of a single function.
+type function struct {
+	name  string // fully qualified, as printed by the compiler
+	insts []inst
+}
+
+type inst struct {
+	pc     int
+	file   string
+	line   int // 0 if unknown
+	op     string
+	target int // branch target, or -1 if op is not a branch
+}
+
+// parse reads the output of the compiler's -S flag. Only text symbols are
+// returned; data symbols and hex dumps are skipped.
+func parse(r io.Reader) []function {
+	var fns []function
+	var cur *function
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "\t") {
+			// a symbol header, "pkg.Name STEXT size=17 ..."
+			cur = nil
+			if fields := strings.Fields(line); len(fields) > 1 && fields[1] == "STEXT" {
+				fns = append(fns, function{name: fields[0]})
+				cur = &fns[len(fns)-1]
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if in, ok := parseInst(line); ok {
+			cur.insts = append(cur.insts, in)
+		}
+	}
+	return fns
+}
+
+// parseInst parses a line of the form
+//
+//	\t0x0004 00004 (/path/to/file.go:19)\tINCQ\tCX
+func parseInst(s string) (inst, bool) {
+	fields := strings.SplitN(strings.TrimPrefix(s, "\t"), "\t", 3)
+	if len(fields) < 2 {
+		return inst{}, false // a hex dump or relocation
+	}
+	head := strings.Fields(fields[0]) // 0x0004 00004 (file:line)
+	if len(head) < 3 || !strings.HasPrefix(head[2], "(") {
+		return inst{}, false
+	}
+	pc, err := strconv.Atoi(head[1])
+	if err != nil {
+		return inst{}, false
+	}
+	in := inst{pc: pc, op: fields[1], target: -1}
+	pos := strings.TrimSuffix(strings.TrimPrefix(strings.Join(head[2:], " "), "("), ")")
+	if i := strings.LastIndexByte(pos, ':'); i > 0 {
+		if n, err := strconv.Atoi(pos[i+1:]); err == nil {
+			in.file, in.line = pos[:i], n
+		}
+	}
+	if isBranch(in.op) && len(fields) == 3 {
+		// the target is the last operand: CBZ and TBZ test a register.
+		args := strings.Split(fields[2], ",")
+		if t, err := strconv.Atoi(strings.TrimSpace(args[len(args)-1])); err == nil {
+			in.target = t
+		}
+	}
+	return in, true
+}
+
+// arm64Branches are the conditional branches of arm64. Its unconditional
+// branch is printed as JMP.
+var arm64Branches = map[string]bool{
+	"BEQ": true, "BNE": true, "BCS": true, "BHS": true, "BCC": true, "BLO": true,
+	"BMI": true, "BPL": true, "BVS": true, "BVC": true, "BHI": true, "BLS": true,
+	"BGE": true, "BLT": true, "BGT": true, "BLE": true,
+	"CBZ": true, "CBZW": true, "CBNZ": true, "CBNZW": true, "TBZ": true, "TBNZ": true,
+}
+
+// isBranch reports whether op is a jump or branch on amd64 or arm64.
+func isBranch(op string) bool {
+	return strings.HasPrefix(op, "J") || arm64Branches[op]
+}
+
+// loop is a benchmark loop with an empty body.
+type loop struct {
+	fn   string
+	file string
+	line int
+}
+
+// deadLoops returns the loops in benchmark functions whose instructions
+// all come from a single source line, a for statement. A loop is found
+// from a backward branch; its instructions are those from the branch target
+// to the branch. Loops from functions inlined from other files, and loops
+// the compiler generates itself, for example to zero a composite literal,
+// are ignored.
+func deadLoops(fns []function) []loop {
+	var loops []loop
+	var src sourceCache
+	for _, fn := range fns {
+		name := shortName(fn.name)
+		if !strings.HasPrefix(name, "Benchmark") || len(fn.insts) == 0 {
+			continue
+		}
+		fnFile := fn.insts[0].file
+		for i, br := range fn.insts {
+			// a branch back to the entry is the stack growth check, not a loop.
+			if br.target <= 0 || br.target > br.pc {
+				continue
+			}
+			file, line, single := "", 0, true
+			for _, in := range fn.insts[:i+1] {
+				if in.pc < br.target || in.line == 0 {
+					continue
+				}
+				if line == 0 {
+					file, line = in.file, in.line
+				} else if in.file != file || in.line != line {
+					single = false
+					break
+				}
+			}
+			if single && line != 0 && file == fnFile && src.isFor(file, line) {
+				loops = append(loops, loop{fn: name, file: file, line: line})
+			}
+		}
+	}
+	return loops
+}
+
+// sourceCache holds the lines of the source files seen so far.
+type sourceCache map[string][]string
+
+// isFor reports whether the given line of file is a for statement.
+func (c *sourceCache) isFor(file string, line int) bool {
+	if *c == nil {
+		*c = make(sourceCache)
+	}
+	lines, ok := (*c)[file]
+	if !ok {
+		data, err := os.ReadFile(file)
+		if err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		(*c)[file] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimSpace(lines[line-1]), "for ")
+}
+
+// shortName strips the import path and package name from a symbol,
+// leaving, for example, BenchmarkPopcnt or BenchmarkPopcnt.func1.
+func shortName(sym string) string {
+	if i := strings.LastIndexByte(sym, '/'); i >= 0 {
+		sym = sym[i+1:]
+	}
+	if i := strings.IndexByte(sym, '.'); i >= 0 {
+		sym = sym[i+1:]
+	}
+	return sym
+}
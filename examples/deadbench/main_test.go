@@ -0,0 +1,137 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// listing is the -S output for BenchmarkPopcnt in examples/popcnt, with
+// FUNCDATA and PCDATA lines removed.
+const listing = `example.com/popcnt.BenchmarkPopcnt STEXT nosplit size=17 args=0x8 locals=0x0 funcid=0x0
+	0x0000 00000 (testdata/popcnt/popcnt_test.go:17)	TEXT	example.com/popcnt.BenchmarkPopcnt(SB), NOSPLIT|NOFRAME|ABIInternal, $0-8
+	0x0000 00000 (testdata/popcnt/popcnt_test.go:18)	XORL	CX, CX
+	0x0002 00002 (testdata/popcnt/popcnt_test.go:18)	JMP	7
+	0x0004 00004 (testdata/popcnt/popcnt_test.go:18)	INCQ	CX
+	0x0007 00007 (testdata/popcnt/popcnt_test.go:18)	CMPQ	528(AX), CX
+	0x000e 00014 (testdata/popcnt/popcnt_test.go:18)	JGT	4
+	0x0010 00016 (testdata/popcnt/popcnt_test.go:21)	RET
+	0x0000 31 c9 eb 03 48 ff c1 48 39 88 10 02 00 00 7f f4  1...H..H9.......
+	0x0010 c3                                               .
+go:cuinfo.packagename.example.com/popcnt SDWARFCUINFO dupok size=0 align=0x0
+	0x0000 6d 61 69 6e                                      main
+`
+
+func TestParse(t *testing.T) {
+	fns := parse(strings.NewReader(listing))
+	if len(fns) != 1 {
+		t.Fatalf("want 1 function, got %d", len(fns))
+	}
+	fn := fns[0]
+	if fn.name != "example.com/popcnt.BenchmarkPopcnt" {
+		t.Fatalf("name: got %q", fn.name)
+	}
+	if len(fn.insts) != 7 {
+		t.Fatalf("want 7 instructions, got %d", len(fn.insts))
+	}
+	want := inst{pc: 14, file: "testdata/popcnt/popcnt_test.go", line: 18, op: "JGT", target: 4}
+	if got := fn.insts[5]; got != want {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestDeadLoopsListing(t *testing.T) {
+	got := deadLoops(parse(strings.NewReader(listing)))
+	want := []loop{{fn: "BenchmarkPopcnt", file: "testdata/popcnt/popcnt_test.go", line: 18}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+// arm64Listing is the -S output for BenchmarkPopcnt compiled for arm64.
+const arm64Listing = `example.com/popcnt.BenchmarkPopcnt STEXT size=32 align=0x0 args=0x8 locals=0x0 funcid=0x0 leaf
+	0x0000 00000 (testdata/popcnt/popcnt_test.go:17)	TEXT	example.com/popcnt.BenchmarkPopcnt(SB), LEAF|NOFRAME|ABIInternal, $0-8
+	0x0000 00000 (testdata/popcnt/popcnt_test.go:18)	MOVD	ZR, R1
+	0x0004 00004 (testdata/popcnt/popcnt_test.go:18)	JMP	12
+	0x0008 00008 (testdata/popcnt/popcnt_test.go:18)	ADD	$1, R1, R1
+	0x000c 00012 (testdata/popcnt/popcnt_test.go:18)	MOVD	528(R0), R2
+	0x0010 00016 (testdata/popcnt/popcnt_test.go:18)	CMP	R2, R1
+	0x0014 00020 (testdata/popcnt/popcnt_test.go:18)	BLT	8
+	0x0018 00024 (testdata/popcnt/popcnt_test.go:21)	RET	(R30)
+`
+
+func TestDeadLoopsARM64(t *testing.T) {
+	got := deadLoops(parse(strings.NewReader(arm64Listing)))
+	want := []loop{{fn: "BenchmarkPopcnt", file: "testdata/popcnt/popcnt_test.go", line: 18}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestParseBranches(t *testing.T) {
+	for line, target := range map[string]int{
+		"\t0x0014 00020 (x.go:1)\tBLT\t8":         8,
+		"\t0x0014 00020 (x.go:1)\tCBNZ\tR1, 4":    4,
+		"\t0x0014 00020 (x.go:1)\tTBZ\t$3, R1, 4": 4,
+		"\t0x0014 00020 (x.go:1)\tJNE\t4":         4,
+		"\t0x0014 00020 (x.go:1)\tBIC\tR1, R2":    -1,
+		"\t0x0014 00020 (x.go:1)\tBL\tfoo(SB)":    -1,
+	} {
+		in, ok := parseInst(line)
+		if !ok || in.target != target {
+			t.Errorf("parseInst(%q): want target %d, got %d, %v", line, target, in.target, ok)
+		}
+	}
+}
+
+func TestShortName(t *testing.T) {
+	for sym, want := range map[string]string{
+		"example.com/popcnt.BenchmarkPopcnt":              "BenchmarkPopcnt",
+		"github.com/a/b-c.BenchmarkFib.func1":             "BenchmarkFib.func1",
+		"main.BenchmarkX":                                 "BenchmarkX",
+		"github.com/a/b.(*T).BenchmarkLike":               "(*T).BenchmarkLike",
+		"github.com/a/b.BenchmarkPopcountBytes.Read.func": "BenchmarkPopcountBytes.Read.func",
+	} {
+		if got := shortName(sym); got != want {
+			t.Errorf("shortName(%q): want %q, got %q", sym, want, got)
+		}
+	}
+}
+
+// TestDeadLoops compiles the fixtures in testdata, which are copies of the
+// benchmarks in examples/popcnt, for each supported architecture.
+func TestDeadLoops(t *testing.T) {
+	for arch := range supported {
+		t.Run(arch, func(t *testing.T) {
+			t.Setenv("GOARCH", arch)
+			testDeadLoops(t)
+		})
+	}
+}
+
+func testDeadLoops(t *testing.T) {
+	tests := map[string][]loop{
+		// popcnt_test.go, the result of popcnt is discarded.
+		"popcnt": {{fn: "BenchmarkPopcnt", file: "popcnt_test.go", line: 18}},
+		// popcnt2_test.go, the result is assigned to a sink.
+		"popcnt2": nil,
+		// popcnt_nobuild.go, the loop is empty to start with.
+		"nobuild": {{fn: "BenchmarkPopcnt", file: "popcnt_test.go", line: 6}},
+	}
+	for dir, want := range tests {
+		t.Run(dir, func(t *testing.T) {
+			asm, err := compile("./" + filepath.Join("testdata", dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := deadLoops(parse(strings.NewReader(string(asm))))
+			for i := range got {
+				got[i].file = filepath.Base(got[i].file)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("want %v, got %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func BenchmarkPopcnt(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		// optimised away
+	}
+}
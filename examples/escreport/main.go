@@ -0,0 +1,138 @@
+// escreport reports the compiler's escape analysis and inlining decisions.
+//
+// It compiles a package with -gcflags=-m=2 and prints one line for each
+// value which escapes to the heap, variable moved to the heap, function
+// which can or cannot be inlined, and call which was inlined, with the
+// enclosing function and the compiler's reason.
+//
+//	go run ./examples/escreport ./examples/esc
+//
+// With -raw it prints the compiler output instead, followed by the
+// function and text of each source line it mentions, which can be saved
+// and compared with a later version of the package using -diff:
+//
+//	go run ./examples/escreport -raw ./examples/esc > before.txt
+//	# edit Sum or NewPoint
+//	go run ./examples/escreport -diff before.txt ./examples/esc
+//
+// Each argument to -diff is a file of saved compiler output, or a package
+// or single .go file to compile. Records are matched by file name, kind, function,
+// symbol and reason, but not line number, so unrelated edits which move
+// code up or down do not show up in the diff.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("escreport: ")
+	raw := flag.Bool("raw", false, "print the compiler output rather than the report")
+	diffMode := flag.Bool("diff", false, "compare two versions: escreport -diff before after")
+	tests := flag.Bool("test", false, "compile the package's test files too")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: escreport [-raw] [-test] [package]\n")
+		fmt.Fprintf(os.Stderr, "       escreport -diff [-test] before after\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *diffMode {
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		before, err := load(flag.Arg(0), *tests)
+		if err != nil {
+			log.Fatal(err)
+		}
+		after, err := load(flag.Arg(1), *tests)
+		if err != nil {
+			log.Fatal(err)
+		}
+		removed, added := diff(before, after)
+		if err := writeDiff(os.Stdout, removed, added); err != nil {
+			log.Fatal(err)
+		}
+		if len(removed)+len(added) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	pkg := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		pkg = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *raw {
+		if err := writeRaw(os.Stdout, pkg, *tests); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	recs, err := load(pkg, *tests)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writeRecords(os.Stdout, recs); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeRaw writes the compiler output for pkg, followed by the function
+// enclosing each position in it, so that load need not look the functions
+// up in source which has been edited since.
+func writeRaw(w io.Writer, pkg string, tests bool) error {
+	out, err := gcdiag.Compile(pkg, tests, "-m=2")
+	if err != nil {
+		return err
+	}
+	diags, err := gcdiag.Parse(bytes.NewReader(out))
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(out); err != nil {
+		return err
+	}
+	return new(gcdiag.Funcs).Write(w, diags)
+}
+
+// load returns the records for arg, which is a file of output saved by
+// -raw, or a package or single .go file to compile.
+func load(arg string, tests bool) ([]record, error) {
+	var out []byte
+	funcs := new(gcdiag.Funcs)
+	if fi, err := os.Stat(arg); err == nil && fi.Mode().IsRegular() && !strings.HasSuffix(arg, ".go") {
+		out, err = os.ReadFile(arg)
+		if err != nil {
+			return nil, err
+		}
+		if err := funcs.Read(bytes.NewReader(out)); err != nil {
+			return nil, err
+		}
+	} else {
+		out, err = gcdiag.Compile(arg, tests, "-m=2")
+		if err != nil {
+			return nil, err
+		}
+	}
+	diags, err := gcdiag.Parse(bytes.NewReader(out))
+	if err != nil {
+		return nil, err
+	}
+	return records(diags, funcs), nil
+}
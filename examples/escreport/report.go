@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+// Kinds of record.
+const (
+	escapes  = "escapes"   // a value escapes to the heap
+	moved    = "moved"     // a variable is moved to the heap
+	inline   = "inline"    // a function can be inlined
+	noInline = "no-inline" // a function cannot be inlined
+	inlined  = "inlined"   // a call was inlined
+)
+
+// record is a single escape analysis or inlining decision.
+type record struct {
+	pos    gcdiag.Pos
+	kind   string
+	fn     string // the function containing pos
+	symbol string // the expression, variable or function concerned
+	reason string
+}
+
+// records extracts the escape analysis and inlining decisions from the
+// output of -gcflags=-m=2. Other diagnostics are ignored.
+//
+// At -m=2 an escaping value is reported twice at the same position: first
+// as "x escapes to heap in F:" followed by the data flow which explains
+// why, then later as "x escapes to heap" or "moved to heap: x". The flow
+// provides the reason for the second message.
+func records(diags []gcdiag.Diagnostic, funcs *gcdiag.Funcs) []record {
+	why := make(map[gcdiag.Pos]string)
+	var recs []record
+	for _, d := range diags {
+		msg := d.Message
+		r := record{pos: d.Pos}
+		switch {
+		case strings.Contains(msg, " escapes to heap in ") && strings.HasSuffix(msg, ":"):
+			why[d.Pos] = flowReason(d.Detail)
+			continue
+		case strings.HasSuffix(msg, " escapes to heap"):
+			r.kind, r.symbol, r.reason = escapes, strings.TrimSuffix(msg, " escapes to heap"), why[d.Pos]
+		case strings.HasPrefix(msg, "moved to heap: "):
+			r.kind, r.symbol, r.reason = moved, strings.TrimPrefix(msg, "moved to heap: "), why[d.Pos]
+		case strings.HasPrefix(msg, "can inline "):
+			r.kind, r.symbol = inline, strings.TrimPrefix(msg, "can inline ")
+			r.symbol, _, _ = strings.Cut(r.symbol, " as: ")
+			if name, cost, ok := strings.Cut(r.symbol, " with "); ok {
+				r.symbol, r.reason = name, cost
+			}
+		case strings.HasPrefix(msg, "cannot inline "):
+			r.kind = noInline
+			r.symbol, r.reason, _ = strings.Cut(strings.TrimPrefix(msg, "cannot inline "), ": ")
+		case strings.HasPrefix(msg, "inlining call to "):
+			r.kind, r.symbol = inlined, strings.TrimPrefix(msg, "inlining call to ")
+		default:
+			continue
+		}
+		r.fn = funcs.At(d.Pos)
+		recs = append(recs, r)
+	}
+	return recs
+}
+
+// flowReason summarises the data flow printed by -m=2, which is made of
+// lines such as
+//
+//	flow: ~r0 ← &x:
+//	  from &x (address-of) at ./p.go:5:9
+//	  from return &x (return) at ./p.go:5:2
+//
+// as the distinct steps in parentheses, "address-of, return".
+func flowReason(detail []string) string {
+	var steps []string
+	for _, line := range detail {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "from ") {
+			continue
+		}
+		if i := strings.LastIndex(line, ") at "); i > 0 {
+			line = line[:i]
+		} else {
+			line = strings.TrimSuffix(line, ")")
+		}
+		j := strings.LastIndex(line, " (")
+		if j < 0 {
+			continue
+		}
+		step := line[j+2:]
+		if !slices.Contains(steps, step) {
+			steps = append(steps, step)
+		}
+	}
+	return strings.Join(steps, ", ")
+}
+
+// writeRecords writes recs as a table.
+func writeRecords(w io.Writer, recs []record) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, r := range recs {
+		writeRecord(tw, "", r)
+	}
+	return tw.Flush()
+}
+
+func writeRecord(w io.Writer, prefix string, r record) {
+	fmt.Fprintf(w, "%s%s:%d\t%s\t%s\t%s", prefix, r.pos.File, r.pos.Line, r.kind, r.fn, r.symbol)
+	if r.reason != "" {
+		fmt.Fprintf(w, "\t%s", r.reason)
+	}
+	fmt.Fprintln(w)
+}
+
+// key identifies a record across two versions of a package. It omits
+// the line number, which changes whenever code above it is edited, and
+// the directory, so that two copies of a package can be compared.
+type key struct {
+	file, kind, fn, symbol, reason string
+}
+
+func keyOf(r record) key {
+	return key{filepath.Base(r.pos.File), r.kind, r.fn, r.symbol, r.reason}
+}
+
+// diff returns the records in before but not after, and those in after
+// but not before. Records with the same key are matched in order, so if a
+// key appears n times in before and m times in after, the last n-m of
+// those in before are removed, or the last m-n of those in after are added.
+func diff(before, after []record) (removed, added []record) {
+	return unmatched(before, after), unmatched(after, before)
+}
+
+// unmatched returns the records in a which are left over when each record
+// in b is matched with the first unmatched record in a with the same key.
+func unmatched(a, b []record) []record {
+	n := make(map[key]int)
+	for _, r := range b {
+		n[keyOf(r)]++
+	}
+	var rs []record
+	for _, r := range a {
+		if k := keyOf(r); n[k] > 0 {
+			n[k]--
+			continue
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// writeDiff writes the output of diff, removed records prefixed with
+// "-" and added records with "+".
+func writeDiff(w io.Writer, removed, added []record) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, r := range removed {
+		writeRecord(tw, "-\t", r)
+	}
+	for _, r := range added {
+		writeRecord(tw, "+\t", r)
+	}
+	return tw.Flush()
+}
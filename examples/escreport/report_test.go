@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+// output is part of the -m=2 output for testdata/before.
+const output = `# github.com/grafana/high-performance-go-workshop/examples/escreport/testdata/before
+testdata/before/center.go:10:6: can inline Center with cost 8 as: func(*Point) { p.X = 320; p.Y = 240 }
+testdata/before/center.go:15:6: cannot inline NewPoint: function too complex: cost 98 exceeds budget 80
+testdata/before/center.go:17:8: inlining call to Center
+testdata/before/center.go:18:15: p.X escapes to heap in NewPoint:
+testdata/before/center.go:18:15:   flow: {storage for ... argument} ← &{storage for p.X}:
+testdata/before/center.go:18:15:     from p.X (spill) at testdata/before/center.go:18:15
+testdata/before/center.go:18:15:     from ... argument (slice-literal-element) at testdata/before/center.go:18:13
+testdata/before/center.go:18:15:   flow: fmt.a ← &{storage for ... argument}:
+testdata/before/center.go:18:15:     from ... argument (spill) at testdata/before/center.go:18:13
+testdata/before/center.go:18:15:     from fmt.a := ... argument (assign-pair) at testdata/before/center.go:18:13
+testdata/before/center.go:18:15:   flow: {heap} ← *fmt.a:
+testdata/before/center.go:18:15:     from fmt.Fprintln(os.Stdout, fmt.a...) (call parameter) at testdata/before/center.go:18:13
+testdata/before/center.go:16:10: new(Point) does not escape
+testdata/before/center.go:18:15: p.X escapes to heap
+testdata/before/sum.go:7:2: numbers escapes to heap in Sum:
+testdata/before/sum.go:7:2:   flow: {heap} ← &numbers:
+testdata/before/sum.go:7:2:     from &numbers (address-of) at testdata/before/sum.go:8:9
+testdata/before/sum.go:7:2: moved to heap: numbers
+`
+
+func TestRecords(t *testing.T) {
+	diags, err := gcdiag.Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := records(diags, new(gcdiag.Funcs))
+	pos := func(file string, line, col int) gcdiag.Pos {
+		return gcdiag.Pos{File: "testdata/before/" + file, Line: line, Col: col}
+	}
+	want := []record{
+		{pos("center.go", 10, 6), inline, "Center", "Center", "cost 8"},
+		{pos("center.go", 15, 6), noInline, "NewPoint", "NewPoint", "function too complex: cost 98 exceeds budget 80"},
+		{pos("center.go", 17, 8), inlined, "NewPoint", "Center", ""},
+		{pos("center.go", 18, 15), escapes, "NewPoint", "p.X", "spill, slice-literal-element, assign-pair, call parameter"},
+		{pos("sum.go", 7, 2), moved, "Sum", "numbers", "address-of"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestFlowReason(t *testing.T) {
+	for _, tt := range []struct {
+		detail []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{
+			"  flow: ~r0 ← &x:",
+			"    from &x (address-of) at ./p.go:5:9",
+			"    from return &x (return) at ./p.go:5:2",
+		}, "address-of, return"},
+		{[]string{
+			"    from t (captured by a closure) at ./p.go:18:18",
+			"    from t (reference) at ./p.go:18:18",
+			"    from t (captured by a closure) at ./p.go:19:18",
+		}, "captured by a closure, reference"},
+	} {
+		if got := flowReason(tt.detail); got != tt.want {
+			t.Errorf("flowReason(%q) = %q, want %q", tt.detail, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	r := func(line int, kind, symbol, reason string) record {
+		return record{pos: gcdiag.Pos{File: "p.go", Line: line}, kind: kind, fn: "F", symbol: symbol, reason: reason}
+	}
+	before := []record{
+		r(1, inline, "F", "cost 8"),
+		r(2, escapes, "x", "return"),
+		r(3, escapes, "x", "return"),
+	}
+	after := []record{
+		r(11, inline, "F", "cost 12"),
+		r(12, escapes, "x", "return"),
+		r(13, moved, "y", "address-of"),
+	}
+	removed, added := diff(before, after)
+	wantRemoved := []record{before[0], before[2]}
+	wantAdded := []record{after[0], after[2]}
+	if !reflect.DeepEqual(removed, wantRemoved) {
+		t.Errorf("removed: got %v, want %v", removed, wantRemoved)
+	}
+	if !reflect.DeepEqual(added, wantAdded) {
+		t.Errorf("added: got %v, want %v", added, wantAdded)
+	}
+}
+
+// TestDiffPackages compiles two versions of examples/esc. In the second,
+// NewPoint returns its Point, which must then be allocated on the heap.
+func TestDiffPackages(t *testing.T) {
+	before, err := load("./testdata/before", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := load("./testdata/after", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, added := diff(before, after)
+	var buf bytes.Buffer
+	writeDiff(&buf, removed, added)
+	found := false
+	for _, r := range added {
+		if r.kind == escapes && r.fn == "NewPoint" && r.symbol == "new(Point)" {
+			found = r.pos == gcdiag.Pos{File: "testdata/after/center.go", Line: 16, Col: 10}
+		}
+	}
+	if !found {
+		t.Fatalf("new(Point) escaping in NewPoint not reported at testdata/after/center.go:16:10:\n%s", &buf)
+	}
+	for _, r := range append(removed, added...) {
+		if r.kind == inline || r.kind == inlined {
+			t.Errorf("unexpected change in inlining:\n%s", &buf)
+		}
+	}
+}
+
+// TestLoadFile checks that a single .go file is compiled, rather than read
+// as saved compiler output.
+func TestLoadFile(t *testing.T) {
+	recs, err := load("../inl/max2.go", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range recs {
+		if r.kind == inline && r.fn == "F" {
+			return
+		}
+	}
+	t.Fatalf("F not reported as inlinable in %v", recs)
+}
+
+// TestLoadSaved checks that records read from saved -raw output keep the
+// functions they were in when it was saved. The saved center.go had nine
+// more lines at the top than the current one, so looking the functions up
+// in the current file would put every record in the wrong one.
+func TestLoadSaved(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeRaw(&buf, "./testdata/before", false); err != nil {
+		t.Fatal(err)
+	}
+	moved := regexp.MustCompile(`center\.go:(\d+)`).ReplaceAllStringFunc(buf.String(), func(s string) string {
+		file, line, _ := strings.Cut(s, ":")
+		n, _ := strconv.Atoi(line)
+		return fmt.Sprintf("%s:%d", file, n+9)
+	})
+	saved := filepath.Join(t.TempDir(), "before.txt")
+	if err := os.WriteFile(saved, []byte(moved), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	before, err := load(saved, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := load("./testdata/before", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed, added := diff(before, after); len(removed)+len(added) > 0 {
+		var buf bytes.Buffer
+		writeDiff(&buf, removed, added)
+		t.Fatalf("unexpected diff:\n%s", &buf)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import "fmt"
+
+// Sum returns the sum of the numbers 1 to count.
+func Sum(count int) int {
+	numbers := make([]int, count)
+	for i := range numbers {
+		numbers[i] = i + 1
+	}
+
+	var sum int
+	for _, i := range numbers {
+		sum += i
+	}
+	return sum
+}
+
+func main() {
+	answer := Sum(100)
+	fmt.Println(answer)
+}
@@ -0,0 +1,62 @@
+package falseshare
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultCacheLineSize is returned by CacheLineSize when the size cannot be
+// determined.
+const DefaultCacheLineSize = 64
+
+// CacheLineSize returns the size, in bytes, of a cache line of the first
+// cpu's level 1 data cache. It is only known on Linux, where it is read
+// from sysfs; elsewhere it returns DefaultCacheLineSize and an error.
+func CacheLineSize() (int, error) {
+	return cacheLineSize(sysfsCacheDir)
+}
+
+// sysfsCacheDir describes the caches of cpu0, one directory per cache.
+const sysfsCacheDir = "/sys/devices/system/cpu/cpu0/cache"
+
+// cacheLineSize reads the line size of the level 1 data cache described
+// in dir. Each cache has a directory, index0, index1 and so on, holding
+// its level, its type, Data, Instruction or Unified, and its
+// coherency_line_size.
+func cacheLineSize(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return DefaultCacheLineSize, err
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "index") {
+			continue
+		}
+		index := dir + "/" + e.Name()
+		level, err := readSysfs(index + "/level")
+		if err != nil || level != "1" {
+			continue
+		}
+		typ, err := readSysfs(index + "/type")
+		if err != nil || typ == "Instruction" {
+			continue
+		}
+		s, err := readSysfs(index + "/coherency_line_size")
+		if err != nil {
+			return DefaultCacheLineSize, err
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return DefaultCacheLineSize, fmt.Errorf("%s/coherency_line_size: invalid size %q", index, s)
+		}
+		return n, nil
+	}
+	return DefaultCacheLineSize, fmt.Errorf("%s: no level 1 data cache", dir)
+}
+
+func readSysfs(name string) (string, error) {
+	b, err := os.ReadFile(name)
+	return strings.TrimSpace(string(b)), err
+}
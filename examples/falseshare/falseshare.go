@@ -0,0 +1,90 @@
+// Package falseshare measures false sharing: the slowdown when goroutines
+// on different cpus write to different variables which happen to share a
+// cache line. Each write takes the line away from the other cpus' caches,
+// so the goroutines contend as if they were writing the same variable.
+package falseshare
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// Layout is the number of bytes between the slots of a Counters.
+type Layout int
+
+const (
+	// Packed slots are adjacent, eight to a 64 byte cache line.
+	Packed Layout = 8
+
+	// Padded64 slots each have a 64 byte cache line to themselves, the
+	// line size of most amd64 and arm64 cpus.
+	Padded64 Layout = 64
+
+	// Padded128 slots each occupy 128 bytes. Intel cpus fetch cache
+	// lines in adjacent pairs, and Apple's arm64 cpus have 128 byte
+	// lines, so 64 bytes of padding is not always enough.
+	Padded128 Layout = 128
+)
+
+func (l Layout) String() string {
+	switch l {
+	case Packed:
+		return "packed"
+	case Padded64:
+		return "padded64"
+	case Padded128:
+		return "padded128"
+	default:
+		return "unknown"
+	}
+}
+
+// Counters is an array of counters, each of which may be incremented by a
+// different goroutine.
+type Counters struct {
+	slots  []uint64
+	stride int // distance, in uint64s, between slots
+	n      int
+}
+
+// New returns n counters laid out as given. Padded counters start on a
+// boundary of their size, so that each is alone on its line, rather than
+// straddling two.
+func New(n int, layout Layout) *Counters {
+	stride := int(layout) / 8
+	slots := make([]uint64, (n+1)*stride)
+	// Skip forward to the first aligned slot. make returns memory
+	// aligned to 8 bytes, at least, so this is always possible.
+	off := 0
+	if rem := uintptr(unsafe.Pointer(&slots[0])) % uintptr(layout); rem != 0 {
+		off = int(uintptr(layout)-rem) / 8
+	}
+	return &Counters{slots: slots[off : off+n*stride], stride: stride, n: n}
+}
+
+// Len returns the number of counters.
+func (c *Counters) Len() int { return c.n }
+
+// Inc atomically increments counter i.
+func (c *Counters) Inc(i int) {
+	atomic.AddUint64(&c.slots[i*c.stride], 1)
+}
+
+// Load atomically loads counter i.
+func (c *Counters) Load(i int) uint64 {
+	return atomic.LoadUint64(&c.slots[i*c.stride])
+}
+
+// Swap atomically stores v in counter i and returns its previous value.
+func (c *Counters) Swap(i int, v uint64) uint64 {
+	return atomic.SwapUint64(&c.slots[i*c.stride], v)
+}
+
+// Sum returns the total of all the counters.
+func (c *Counters) Sum() uint64 {
+	var sum uint64
+	for i := range c.n {
+		sum += c.Load(i)
+	}
+	return sum
+}
@@ -0,0 +1,142 @@
+package falseshare
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+)
+
+var layouts = []Layout{Packed, Padded64, Padded128}
+
+func TestNew(t *testing.T) {
+	for _, layout := range layouts {
+		for _, n := range []int{1, 3, 8, 64} {
+			c := New(n, layout)
+			if c.Len() != n {
+				t.Fatalf("%v: Len() = %d, want %d", layout, c.Len(), n)
+			}
+			for i := range n {
+				addr := uintptr(unsafe.Pointer(&c.slots[i*c.stride]))
+				if addr%uintptr(layout) != 0 {
+					t.Fatalf("%v: slot %d at %#x is not aligned to %d bytes", layout, i, addr, layout)
+				}
+			}
+			if got, want := len(c.slots), n*int(layout)/8; got != want {
+				t.Fatalf("%v: %d uint64s for %d slots, want %d", layout, got, n, want)
+			}
+		}
+	}
+}
+
+func TestInc(t *testing.T) {
+	const goroutines, incs = 8, 1000
+	for _, layout := range layouts {
+		c := New(goroutines, layout)
+		var wg sync.WaitGroup
+		for g := range goroutines {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range incs * (g + 1) {
+					c.Inc(g)
+				}
+			}()
+		}
+		wg.Wait()
+		for g := range goroutines {
+			if got, want := c.Load(g), uint64(incs*(g+1)); got != want {
+				t.Errorf("%v: counter %d = %d, want %d", layout, g, got, want)
+			}
+		}
+		if got, want := c.Sum(), uint64(incs*goroutines*(goroutines+1)/2); got != want {
+			t.Errorf("%v: Sum() = %d, want %d", layout, got, want)
+		}
+		for g := range goroutines {
+			if got, want := c.Swap(g, 0), uint64(incs*(g+1)); got != want {
+				t.Errorf("%v: Swap(%d, 0) = %d, want %d", layout, g, got, want)
+			}
+		}
+		if got := c.Sum(); got != 0 {
+			t.Errorf("%v: Sum() after Swap = %d, want 0", layout, got)
+		}
+	}
+}
+
+func TestCacheLineSize(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		if n, err := CacheLineSize(); err == nil || n != DefaultCacheLineSize {
+			t.Fatalf("CacheLineSize() = %d, %v on %s", n, err, runtime.GOOS)
+		}
+		return
+	}
+	n, err := CacheLineSize()
+	if err != nil {
+		t.Skipf("no cache information in sysfs: %v", err)
+	}
+	if n <= 0 || n&(n-1) != 0 {
+		t.Fatalf("CacheLineSize() = %d, want a power of two", n)
+	}
+	t.Logf("cache line size: %d bytes", n)
+}
+
+func TestCacheLineSizeSysfs(t *testing.T) {
+	for _, tt := range []struct {
+		dir  string
+		want int
+		err  bool
+	}{
+		// index0 is the instruction cache, index1 the data cache.
+		{"testdata/cpu0/cache", 128, false},
+		{"testdata/nol1/cache", DefaultCacheLineSize, true},
+		{"testdata/missing", DefaultCacheLineSize, true},
+	} {
+		n, err := cacheLineSize(tt.dir)
+		if n != tt.want || (err != nil) != tt.err {
+			t.Errorf("%s: got %d, %v; want %d, error %v", tt.dir, n, err, tt.want, tt.err)
+		}
+	}
+}
+
+// benchmarkCounters increments a counter per goroutine. RunParallel starts
+// a goroutine per P, so with -cpu 4, say, four cpus write to four counters
+// at once.
+func benchmarkCounters(b *testing.B, c *Counters) {
+	var next int64
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(atomic.AddInt64(&next, 1)-1) % c.Len()
+		for pb.Next() {
+			c.Inc(i)
+		}
+	})
+}
+
+func BenchmarkCounters(b *testing.B) {
+	for _, layout := range layouts {
+		b.Run(layout.String(), func(b *testing.B) {
+			benchmarkCounters(b, New(runtime.GOMAXPROCS(0), layout))
+		})
+	}
+}
+
+// BenchmarkShared increments a single counter from every goroutine, the
+// true sharing which packed counters suffer from by accident.
+func BenchmarkShared(b *testing.B) {
+	benchmarkCounters(b, New(1, Packed))
+}
+
+var Result uint64
+
+func BenchmarkSum(b *testing.B) {
+	for _, layout := range layouts {
+		b.Run(layout.String(), func(b *testing.B) {
+			c := New(runtime.GOMAXPROCS(0), layout)
+			var r uint64
+			for i := 0; i < b.N; i++ {
+				r = c.Sum()
+			}
+			Result = r
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package fib computes Fibonacci numbers in several ways, from the
+// exponential definition to arbitrary precision. All of them except Fib,
+// which is kept as the benchmarking chapter shows it, panic if n is
+// negative.
+package fib
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// MaxN is the largest n for which the n'th Fibonacci number fits in an int:
+// 92 on 64 bit platforms, 46 on 32 bit ones. The int variants below
+// silently overflow for larger n; use Checked or Big instead.
+const MaxN = 46 + 46*(bits.UintSize/64)
+
+// ErrOverflow is returned by Checked when the result does not fit in an int.
+var ErrOverflow = errors.New("fib: result overflows int")
+
+// errNegative is the value the variants panic with when n is negative.
+var errNegative = errors.New("fib: negative n")
+
+func checkN(n int) {
+	if n < 0 {
+		panic(errNegative)
+	}
+}
+
+// Fib computes the n'th number in the Fibonacci series. tag::fib[]
+func Fib(n int) int {
+	switch n {
+	case 0:
+		return 0
+	case 1:
+		return 1
+	default:
+		return Fib(n-1) + Fib(n-2)
+	}
+}
+
+// end::fib[]
+
+// Recursive computes the n'th Fibonacci number by the definition, as Fib
+// does, but panics if n is negative rather than recursing until the stack
+// overflows. It takes time exponential in n.
+func Recursive(n int) int {
+	checkN(n)
+	return Fib(n)
+}
+
+// Memo computes Fibonacci numbers recursively, remembering each result so
+// it is computed only once. The cache is kept between calls, so a Memo can
+// be reused to amortise its cost. The zero value is ready to use.
+type Memo struct {
+	cache []int // cache[n] is F(n), or 0 if not yet computed
+}
+
+// Fib returns the n'th Fibonacci number.
+func (m *Memo) Fib(n int) int {
+	checkN(n)
+	if n < 2 {
+		return n
+	}
+	if n >= len(m.cache) {
+		m.cache = append(m.cache, make([]int, n+1-len(m.cache))...)
+	}
+	if m.cache[n] == 0 {
+		m.cache[n] = m.Fib(n-1) + m.Fib(n-2)
+	}
+	return m.cache[n]
+}
+
+// Iterative computes the n'th Fibonacci number in n steps.
+func Iterative(n int) int {
+	checkN(n)
+	a, b := 0, 1
+	for i := 0; i < n; i++ {
+		a, b = b, a+b
+	}
+	return a
+}
+
+// Checked is Iterative with overflow detection. It returns ErrOverflow if
+// F(n) does not fit in an int.
+func Checked(n int) (int, error) {
+	checkN(n)
+	if n < 2 {
+		return n, nil
+	}
+	a, b := 0, 1 // F(i-1), F(i)
+	for i := 1; i < n; i++ {
+		if a > math.MaxInt-b {
+			return 0, ErrOverflow
+		}
+		a, b = b, a+b
+	}
+	return b, nil
+}
+
+// FastDoubling computes the n'th Fibonacci number in O(log n) steps using
+// the identities
+//
+//	F(2k)   = F(k) * (2*F(k+1) - F(k))
+//	F(2k+1) = F(k+1)^2 + F(k)^2
+func FastDoubling(n int) int {
+	checkN(n)
+	a, b := 0, 1 // F(k), F(k+1), starting at k = 0
+	for i := bits.Len(uint(n)) - 1; i >= 0; i-- {
+		a, b = a*(2*b-a), a*a+b*b // k = 2k
+		if n>>i&1 == 1 {
+			a, b = b, a+b // k = k+1
+		}
+	}
+	return a
+}
+
+// Matrix computes the n'th Fibonacci number by raising the matrix
+//
+//	| 1 1 |
+//	| 1 0 |
+//
+// to the n'th power by repeated squaring, the top right element of the
+// result is F(n).
+func Matrix(n int) int {
+	checkN(n)
+	// r and m are symmetric 2x2 matrices, stored as {top left, top right,
+	// bottom right}.
+	r := [3]int{1, 0, 1}
+	m := [3]int{1, 1, 0}
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			r = mul(r, m)
+		}
+		m = mul(m, m)
+	}
+	return r[1]
+}
+
+// mul multiplies two symmetric 2x2 matrices. Powers of the same symmetric
+// matrix commute, so their product is also symmetric.
+func mul(x, y [3]int) [3]int {
+	return [3]int{
+		x[0]*y[0] + x[1]*y[1],
+		x[0]*y[1] + x[1]*y[2],
+		x[1]*y[1] + x[2]*y[2],
+	}
+}
+
+// Big computes the n'th Fibonacci number to arbitrary precision using fast
+// doubling.
+func Big(n int) *big.Int {
+	checkN(n)
+	a, b := big.NewInt(0), big.NewInt(1)
+	var t, u big.Int
+	for i := bits.Len(uint(n)) - 1; i >= 0; i-- {
+		t.Lsh(b, 1)
+		t.Sub(&t, a)
+		t.Mul(&t, a) // F(2k) = F(k) * (2*F(k+1) - F(k))
+		u.Mul(a, a)
+		b.Mul(b, b)
+		b.Add(b, &u) // F(2k+1) = F(k+1)^2 + F(k)^2
+		a.Set(&t)
+		if n>>i&1 == 1 {
+			a.Add(a, b)
+			a, b = b, a
+		}
+	}
+	return a
+}
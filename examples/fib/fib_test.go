@@ -1,20 +1,10 @@
 package fib
 
-import "testing"
-
-// Fib computes the n'th number in the Fibonacci series. tag::fib[]
-func Fib(n int) int {
-	switch n {
-	case 0:
-		return 0
-	case 1:
-		return 1
-	default:
-		return Fib(n-1) + Fib(n-2)
-	}
-}
-
-// end::fib[]
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
 
 // tag::benchmarkfib20[]
 func BenchmarkFib20(b *testing.B) {
@@ -41,10 +31,124 @@ func TestFib(t *testing.T) {
 	}
 }
 
-func Fib2(n int) int {
-	a, b := 0, 1
-	for i := 0; i < n; i++ {
-		a, b = b, a+b
+func TestVariants(t *testing.T) {
+	variants := map[string]func(int) int{
+		"Iterative":    Iterative,
+		"FastDoubling": FastDoubling,
+		"Matrix":       Matrix,
+		"Memo":         new(Memo).Fib,
+	}
+	for name, fn := range variants {
+		t.Run(name, func(t *testing.T) {
+			for n := 0; n <= MaxN; n++ {
+				want := Big(n)
+				if got := fn(n); !want.IsInt64() || int64(got) != want.Int64() {
+					t.Fatalf("%s(%d): want %v, got %d", name, n, want, got)
+				}
+			}
+		})
+	}
+	for n := 0; n <= 25; n++ {
+		if got, want := Fib(n), Iterative(n); got != want {
+			t.Fatalf("Fib(%d): want %d, got %d", n, want, got)
+		}
+	}
+}
+
+func TestChecked(t *testing.T) {
+	got, err := Checked(MaxN)
+	if err != nil || got != Iterative(MaxN) {
+		t.Fatalf("Checked(%d): want %d, got %d, %v", MaxN, Iterative(MaxN), got, err)
+	}
+	if _, err := Checked(MaxN + 1); err != ErrOverflow {
+		t.Fatalf("Checked(%d): want %v, got %v", MaxN+1, ErrOverflow, err)
+	}
+}
+
+func TestNegative(t *testing.T) {
+	variants := map[string]func(int){
+		"Recursive":    func(n int) { Recursive(n) },
+		"Memo":         func(n int) { new(Memo).Fib(n) },
+		"Iterative":    func(n int) { Iterative(n) },
+		"Checked":      func(n int) { Checked(n) },
+		"FastDoubling": func(n int) { FastDoubling(n) },
+		"Matrix":       func(n int) { Matrix(n) },
+		"Big":          func(n int) { Big(n) },
+	}
+	for name, fn := range variants {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != errNegative {
+					t.Fatalf("%s(-1): want panic %v, got %v", name, errNegative, r)
+				}
+			}()
+			fn(-1)
+		})
+	}
+}
+
+func TestBig(t *testing.T) {
+	// F(100) and F(300), from OEIS A000045.
+	for n, want := range map[int]string{
+		100: "354224848179261915075",
+		300: "222232244629420445529739893461909967206666939096499764990979600",
+	} {
+		if got := Big(n).String(); got != want {
+			t.Errorf("Big(%d): want %s, got %s", n, want, got)
+		}
+	}
+
+	// F(n+1) = F(n) + F(n-1), well beyond the range of an int.
+	var sum big.Int
+	for n := 1; n < 1000; n += 37 {
+		sum.Add(Big(n), Big(n-1))
+		if Big(n+1).Cmp(&sum) != 0 {
+			t.Fatalf("Big(%d) != Big(%d) + Big(%d)", n+1, n, n-1)
+		}
+	}
+}
+
+var Result int
+
+func BenchmarkVariants(b *testing.B) {
+	variants := []struct {
+		name string
+		fn   func(int) int
+		maxN int // largest n worth benchmarking
+	}{
+		{"Fib", Fib, 30},
+		{"Memo", new(Memo).Fib, MaxN}, // the cache is warm after the first call
+		{"Iterative", Iterative, MaxN},
+		{"FastDoubling", FastDoubling, MaxN},
+		{"Matrix", Matrix, MaxN},
+	}
+	for _, n := range []int{10, 20, 30, 90} {
+		for _, v := range variants {
+			if n > v.maxN {
+				continue
+			}
+			b.Run(fmt.Sprintf("n=%d/%s", n, v.name), func(b *testing.B) {
+				var r int
+				for i := 0; i < b.N; i++ {
+					r = v.fn(n)
+				}
+				Result = r
+			})
+		}
+	}
+}
+
+var BigResult *big.Int
+
+func BenchmarkBig(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			var r *big.Int
+			for i := 0; i < b.N; i++ {
+				r = Big(n)
+			}
+			BigResult = r
+		})
 	}
-	return a
 }
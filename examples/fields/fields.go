@@ -15,7 +15,7 @@ type S struct {
 // end::struct[]
 
 // tag::padding[]
-type S struct {
+type SPadded struct {
 	a bool
 	_ [7]byte // padding <1>
 	b float64
@@ -30,4 +30,5 @@ func main() {
 	var s S
 	fmt.Println(unsafe.Sizeof(s)) // <1>
 	// end::sizeof[]
+	fmt.Println(unsafe.Sizeof(SPadded{}))
 }
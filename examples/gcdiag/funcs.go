@@ -0,0 +1,187 @@
+package gcdiag
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Funcs finds the function enclosing a source position. Files are parsed
+// the first time they are asked about, unless Read has been called. The
+// zero value is ready to use.
+type Funcs struct {
+	files map[string]*funcFile
+	saved bool // the files are known only from Read
+}
+
+type funcFile struct {
+	lines []string // the source, for Line
+	decls []funcDecl
+}
+
+type funcDecl struct {
+	name       string
+	start, end int // lines
+}
+
+// At returns the name of the top level function or method declared
+// around pos, for example "Sum" or "(*T).M". Closures are reported as
+// their enclosing function. At returns "" if pos is not inside a function,
+// or the file cannot be parsed.
+func (f *Funcs) At(pos Pos) string {
+	ff := f.file(pos.File)
+	for _, d := range ff.decls {
+		if d.start <= pos.Line && pos.Line <= d.end {
+			return d.name
+		}
+	}
+	return ""
+}
+
+// Line returns the text of the source line at pos, or "" if it is not
+// known.
+func (f *Funcs) Line(pos Pos) string {
+	ff := f.file(pos.File)
+	if pos.Line < 1 || pos.Line > len(ff.lines) {
+		return ""
+	}
+	return ff.lines[pos.Line-1]
+}
+
+func (f *Funcs) file(name string) *funcFile {
+	if ff, ok := f.files[name]; ok {
+		return ff
+	}
+	if f.files == nil {
+		f.files = make(map[string]*funcFile)
+	}
+	ff := new(funcFile)
+	f.files[name] = ff
+	if f.saved {
+		return ff
+	}
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return ff
+	}
+	ff.lines = strings.Split(string(src), "\n")
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+	if err != nil {
+		return ff
+	}
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		ff.decls = append(ff.decls, funcDecl{
+			name:  FuncName(fd),
+			start: fset.Position(fd.Pos()).Line,
+			end:   fset.Position(fd.End()).Line,
+		})
+	}
+	return ff
+}
+
+// srcPrefix starts the lines written by Write. Parse skips them, as they
+// do not start with a position.
+const srcPrefix = "#src "
+
+// Write writes a line for each source line mentioned in diags, giving the
+// function it is in and its text, such as
+//
+//	#src ./p.go:5<tab>F<tab><tab>return &x
+//
+// Appended to saved compiler output, these lines let Read recover the
+// functions and source lines after the source has been edited.
+func (f *Funcs) Write(w io.Writer, diags []Diagnostic) error {
+	bw := bufio.NewWriter(w)
+	seen := make(map[Pos]bool)
+	for _, d := range diags {
+		pos := Pos{File: d.Pos.File, Line: d.Pos.Line}
+		if seen[pos] {
+			continue
+		}
+		seen[pos] = true
+		fmt.Fprintf(bw, "%s%s:%d\t%s\t%s\n", srcPrefix, pos.File, pos.Line, f.At(pos), f.Line(pos))
+	}
+	return bw.Flush()
+}
+
+// Read reads the lines written by Write, skipping any others. From then
+// on At and Line answer from what was read, and no longer read the source
+// files, which may have changed since.
+func (f *Funcs) Read(r io.Reader) error {
+	f.saved = true
+	if f.files == nil {
+		f.files = make(map[string]*funcFile)
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		rest, ok := strings.CutPrefix(sc.Text(), srcPrefix)
+		if !ok {
+			continue
+		}
+		fields := strings.SplitN(rest, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		i := strings.LastIndex(fields[0], ":")
+		if i < 0 {
+			continue
+		}
+		name := fields[0][:i]
+		line, err := strconv.Atoi(fields[0][i+1:])
+		if err != nil || line < 1 {
+			continue
+		}
+		ff, ok := f.files[name]
+		if !ok {
+			ff = new(funcFile)
+			f.files[name] = ff
+		}
+		for len(ff.lines) < line {
+			ff.lines = append(ff.lines, "")
+		}
+		ff.lines[line-1] = fields[2]
+		if fields[1] != "" {
+			ff.decls = append(ff.decls, funcDecl{name: fields[1], start: line, end: line})
+		}
+	}
+	return sc.Err()
+}
+
+// FuncName returns the name of fd as the compiler prints it, for example
+// "Sum", "T.Get" or "(*T).Set". Type parameters are omitted.
+func FuncName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	typ := fd.Recv.List[0].Type
+	star := false
+	if s, ok := typ.(*ast.StarExpr); ok {
+		typ, star = s.X, true
+	}
+	switch t := typ.(type) {
+	case *ast.IndexExpr:
+		typ = t.X
+	case *ast.IndexListExpr:
+		typ = t.X
+	}
+	recv := "?"
+	if id, ok := typ.(*ast.Ident); ok {
+		recv = id.Name
+	}
+	if star {
+		return "(*" + recv + ")." + fd.Name.Name
+	}
+	return recv + "." + fd.Name.Name
+}
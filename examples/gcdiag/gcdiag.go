@@ -0,0 +1,156 @@
+// Package gcdiag runs the compiler with diagnostic flags, such as -m or
+// -d=ssa/check_bce/debug=1, and parses what it prints. It is shared by the
+// escreport, inlbudget, bcereport and provereport commands.
+package gcdiag
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Pos is a position in a source file, as printed by the compiler.
+type Pos struct {
+	File      string
+	Line, Col int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+// Diagnostic is a single message from the compiler.
+type Diagnostic struct {
+	Pos     Pos
+	Message string
+
+	// Detail holds the indented lines which follow some messages at the
+	// same position, for example the flow explanation printed by -m=2.
+	// The indentation is preserved.
+	Detail []string
+}
+
+// Compile compiles pkg with the given -gcflags, discarding the result, and
+// returns what the compiler printed. If tests is true the package's test
+// files are compiled too, which is needed when the code of interest is in
+// a _test.go file. The flags apply only to pkg, not its dependencies.
+//
+// File names in the output are relative to the current directory. The go
+// command caches compiler output and replays it with the file names as
+// they were when the package was first compiled, which may have been from
+// another directory, so Compile builds with -trimpath and maps the import
+// paths this leaves in the output back to directories itself. pkg may
+// also be a single .go file, in which case its build constraints are
+// ignored, as they are by go build.
+func Compile(pkg string, tests bool, gcflags string) ([]byte, error) {
+	dirs, err := list(pkg)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"build", "-trimpath", "-o", os.DevNull, "-gcflags=" + gcflags, pkg}
+	if tests {
+		args = []string{"test", "-c", "-trimpath", "-o", os.DevNull, "-gcflags=" + gcflags, pkg}
+	}
+	cmd := exec.Command("go", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %v\n%s", pkg, err, stderr.Bytes())
+	}
+	out := stderr.Bytes()
+	for importPath, dir := range dirs {
+		out = bytes.ReplaceAll(out, []byte(importPath+"/"), []byte(dir))
+		if importPath == "command-line-arguments" {
+			// pkg is a list of files, whose names -trimpath leaves
+			// relative to their directory.
+			out = bytes.ReplaceAll(out, []byte("\n./"), []byte("\n"+dir))
+		}
+	}
+	return out, nil
+}
+
+// list returns the directories of the packages matched by pkg, keyed by
+// import path. Each directory is relative to the current one if possible,
+// and ends in a slash, for example "examples/esc/" or "./".
+func list(pkg string) (map[string]string, error) {
+	cmd := exec.Command("go", "list", "-f", "{{.ImportPath}}\t{{.Dir}}", pkg)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v\n%s", pkg, err, stderr.Bytes())
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		importPath, dir, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		if rel, err := filepath.Rel(wd, dir); err == nil {
+			dir = rel
+		}
+		if dir == "." {
+			dirs[importPath] = "./"
+		} else {
+			dirs[importPath] = dir + string(filepath.Separator)
+		}
+	}
+	return dirs, nil
+}
+
+// Parse reads compiler output. Lines which do not start with a position,
+// such as the "# pkg" headers printed by the go command, are skipped.
+func Parse(r io.Reader) ([]Diagnostic, error) {
+	var diags []Diagnostic
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		pos, msg, ok := parseLine(sc.Text())
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(msg, " ") {
+			if n := len(diags); n > 0 && diags[n-1].Pos == pos {
+				diags[n-1].Detail = append(diags[n-1].Detail, msg)
+			}
+			continue
+		}
+		diags = append(diags, Diagnostic{Pos: pos, Message: msg})
+	}
+	return diags, sc.Err()
+}
+
+// parseLine splits a line of the form
+//
+//	./center.go:19:15: p.X escapes to heap
+//
+// into its position and message. The single space after the position is
+// removed; any further indentation is kept.
+func parseLine(s string) (Pos, string, bool) {
+	// The file name may itself contain colons, so work from the ".go:".
+	i := strings.Index(s, ".go:")
+	if i < 0 {
+		return Pos{}, "", false
+	}
+	file, rest := s[:i+3], s[i+4:]
+	fields := strings.SplitN(rest, ":", 3)
+	if len(fields) != 3 {
+		return Pos{}, "", false
+	}
+	line, err1 := strconv.Atoi(fields[0])
+	col, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil {
+		return Pos{}, "", false
+	}
+	return Pos{File: file, Line: line, Col: col}, strings.TrimPrefix(fields[2], " "), true
+}
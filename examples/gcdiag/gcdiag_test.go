@@ -0,0 +1,137 @@
+package gcdiag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const output = `# example.com/p
+./p.go:3:6: can inline F with cost 8 as: func() *int { x := 1; return &x }
+./p.go:4:2: x escapes to heap in F:
+./p.go:4:2:   flow: ~r0 ← &x:
+./p.go:4:2:     from &x (address-of) at ./p.go:5:9
+./p.go:4:2: moved to heap: x
+C:\work\p.go:10:7: t does not escape
+`
+
+func TestParse(t *testing.T) {
+	got, err := Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Diagnostic{
+		{Pos: Pos{"./p.go", 3, 6}, Message: "can inline F with cost 8 as: func() *int { x := 1; return &x }"},
+		{Pos: Pos{"./p.go", 4, 2}, Message: "x escapes to heap in F:", Detail: []string{
+			"  flow: ~r0 ← &x:",
+			"    from &x (address-of) at ./p.go:5:9",
+		}},
+		{Pos: Pos{"./p.go", 4, 2}, Message: "moved to heap: x"},
+		{Pos: Pos{`C:\work\p.go`, 10, 7}, Message: "t does not escape"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	var f Funcs
+	for _, tt := range []struct {
+		line int
+		want string
+	}{
+		{1, ""},
+		{4, "F"},
+		{10, "(*T).M"},
+		{14, "H"}, // inside a closure
+		{100, ""},
+	} {
+		if got := f.At(Pos{File: "testdata/p/p.go", Line: tt.line}); got != tt.want {
+			t.Errorf("line %d: got %q, want %q", tt.line, got, tt.want)
+		}
+	}
+	if got, want := f.Line(Pos{File: "testdata/p/p.go", Line: 5}), "\treturn &x"; got != want {
+		t.Errorf("Line: got %q, want %q", got, want)
+	}
+	if got := f.At(Pos{File: "testdata/missing.go", Line: 1}); got != "" {
+		t.Errorf("missing file: got %q", got)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	diags := []Diagnostic{
+		{Pos: Pos{"testdata/p/p.go", 4, 2}, Message: "x escapes to heap in F:"},
+		{Pos: Pos{"testdata/p/p.go", 4, 2}, Message: "moved to heap: x"},
+		{Pos: Pos{"testdata/p/p.go", 5, 9}, Message: "&x escapes to heap"},
+		{Pos: Pos{"testdata/p/p.go", 1, 1}, Message: "package level"},
+	}
+	var buf strings.Builder
+	if err := new(Funcs).Write(&buf, diags); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Errorf("wrote %d lines, want one for each source line:\n%s", n, &buf)
+	}
+	// Parse must skip the saved lines.
+	if got, err := Parse(strings.NewReader(buf.String())); err != nil || len(got) != 0 {
+		t.Errorf("Parse: got %v, %v", got, err)
+	}
+
+	// Saved lines take the place of the source, which may since have
+	// changed, or gone.
+	saved := buf.String() + srcPrefix + "testdata/gone.go:3\tG\tgo g()\n"
+	var f Funcs
+	if err := f.Read(strings.NewReader(output + saved)); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		pos      Pos
+		fn, line string
+	}{
+		{Pos{"testdata/p/p.go", 4, 2}, "F", "\tx := 1"},
+		{Pos{"testdata/p/p.go", 5, 9}, "F", "\treturn &x"},
+		{Pos{"testdata/p/p.go", 1, 1}, "", "package p"},
+		{Pos{"testdata/p/p.go", 10, 1}, "", ""}, // not saved, so not read
+		{Pos{"testdata/gone.go", 3, 2}, "G", "go g()"},
+	} {
+		if got := f.At(tt.pos); got != tt.fn {
+			t.Errorf("At(%v) = %q, want %q", tt.pos, got, tt.fn)
+		}
+		if got := f.Line(tt.pos); got != tt.line {
+			t.Errorf("Line(%v) = %q, want %q", tt.pos, got, tt.line)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	out, err := Compile("./testdata/p", false, "-m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags, err := Parse(strings.NewReader(string(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, d := range diags {
+		if d.Message == "moved to heap: x" {
+			found = d.Pos == Pos{"testdata/p/p.go", 4, 2}
+		}
+	}
+	if !found {
+		t.Fatalf("no moved to heap: x at testdata/p/p.go:4:2 in:\n%s", out)
+	}
+	if _, err := Compile("./testdata/missing", false, "-m"); err == nil {
+		t.Fatal("compiling a missing package succeeded")
+	}
+}
+
+func TestCompileFile(t *testing.T) {
+	out, err := Compile("testdata/p/p.go", false, "-m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "\ntestdata/p/p.go:4:2: moved to heap: x\n") {
+		t.Fatalf("positions not relative to the current directory:\n%s", out)
+	}
+}
@@ -0,0 +1,15 @@
+package p
+
+func F() *int {
+	x := 1
+	return &x
+}
+
+type T struct{ a [100]int }
+
+func (t *T) M() int { return t.a[0] }
+
+func H() {
+	var t T
+	go func() { _ = t.M() }()
+}
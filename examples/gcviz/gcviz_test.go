@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPauseDelta(t *testing.T) {
+	buckets := []float64{math.Inf(-1), 0.001, 0.002, 0.004, math.Inf(1)}
+	prev := []uint64{0, 5, 1, 0}
+	cur := []uint64{0, 7, 1, 1}
+	n, total, longest := pauseDelta(buckets, prev, cur)
+	if n != 3 {
+		t.Errorf("pauses: got %d, want 3", n)
+	}
+	// Two pauses in [1ms, 2ms) and one in [4ms, +Inf).
+	if want := 2*1500*time.Microsecond + 4*time.Millisecond; total != want {
+		t.Errorf("total: got %v, want %v", total, want)
+	}
+	if want := 4 * time.Millisecond; longest != want {
+		t.Errorf("longest: got %v, want %v", longest, want)
+	}
+
+	// Pauses in the unbounded buckets count at their finite bound.
+	n, total, longest = pauseDelta(buckets, []uint64{0, 0, 0, 0}, []uint64{1, 0, 0, 1})
+	if n != 2 || total != 5*time.Millisecond || longest != 4*time.Millisecond {
+		t.Errorf("unbounded buckets: got %d, %v, %v; want 2, 5ms, 4ms", n, total, longest)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	s := newSampler()
+	before := s.sample()
+	runtime.GC()
+	after := s.sample()
+	if after.cycles <= before.cycles {
+		t.Errorf("cycles: %d after runtime.GC, %d before", after.cycles, before.cycles)
+	}
+	if after.pauses < 2 {
+		t.Errorf("pauses: got %d after runtime.GC, want at least 2", after.pauses)
+	}
+	if after.goal == 0 {
+		t.Error("heap goal is 0")
+	}
+}
+
+func TestWorkloads(t *testing.T) {
+	for name, w := range workloads {
+		t.Run(name, func(t *testing.T) {
+			samples := run(w, 4<<20, 300*time.Millisecond, 5*time.Millisecond)
+			if len(samples) < 2 {
+				t.Fatalf("got %d samples", len(samples))
+			}
+			for i, s := range samples[1:] {
+				if s.t < samples[i].t || s.cycles < samples[i].cycles {
+					t.Fatalf("sample %d goes backwards: %+v after %+v", i+1, s, samples[i])
+				}
+			}
+			first, last := samples[0], samples[len(samples)-1]
+			if last.cycles == first.cycles {
+				t.Errorf("no GC cycles in %v", last.t-first.t)
+			}
+		})
+	}
+}
+
+var testSamples = []sample{
+	{t: 0, goal: 4 << 20, cycles: 1},
+	{t: 5 * time.Millisecond, goal: 8 << 20, live: 3 << 20, objects: 5 << 20, cycles: 3, pauses: 4, pauseTotal: 100 * time.Microsecond, pauseMax: 32 * time.Microsecond},
+	{t: 10 * time.Millisecond, goal: 8 << 20, live: 3 << 20, objects: 7 << 20, cycles: 3},
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	writeCSV(w, testSamples)
+	w.Flush()
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(testSamples)+1 {
+		t.Fatalf("got %d records, want %d", len(records), len(testSamples)+1)
+	}
+	want := "5.0,8388608,3145728,5242880,3,4,100.0,32.0"
+	if got := strings.Join(records[2], ","); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	for _, samples := range [][]sample{testSamples, testSamples[:1], nil} {
+		var buf bytes.Buffer
+		if err := writeSVG(&buf, "a <title> & more", samples); err != nil {
+			t.Fatal(err)
+		}
+		elements := map[string]int{}
+		d := xml.NewDecoder(&buf)
+		for {
+			tok, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%d samples: %v", len(samples), err)
+			}
+			if se, ok := tok.(xml.StartElement); ok {
+				elements[se.Name.Local]++
+			}
+		}
+		if len(samples) == 0 {
+			continue
+		}
+		if got := elements["polyline"]; got != len(heapSeries) {
+			t.Errorf("%d samples: got %d polylines, want %d", len(samples), got, len(heapSeries))
+		}
+	}
+}
+
+func TestParseGOGC(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"100", 100, true},
+		{"0", 0, true},
+		{"off", -1, true},
+		{"OFF", -1, true},
+		{"-5", 0, false},
+		{"lots", 0, false},
+	} {
+		got, err := parseGOGC(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseGOGC(%q) = %d, %v", tt.in, got, err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// gcviz shows how GOGC and GOMEMLIMIT change the way the garbage collector
+// paces itself. It runs an allocation workload, samples runtime/metrics
+// every few milliseconds, and writes the samples as CSV to stdout. With
+// -svg it also draws a chart of heap goal, live heap, GC cycles and pause
+// times.
+//
+//	go run ./examples/gcviz -workload retain -live 64MB > gogc100.csv
+//	go run ./examples/gcviz -workload retain -live 64MB -gogc 400 -svg gogc400.svg
+//	go run ./examples/gcviz -workload retain -live 64MB -gogc off -memlimit 256MB
+//
+// The workloads are
+//
+//	steady	short-lived objects allocated at a constant rate
+//	bursty	short-lived objects allocated in 100ms bursts, 200ms apart
+//	retain	a long-lived set of -live bytes, like inuseallocs, with one
+//		object replaced for each allocation
+//
+// A summary of the run is printed to stderr.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/high-performance-go-workshop/examples/bytesize"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gcviz: ")
+	name := flag.String("workload", "steady", "allocation `workload`: steady, bursty or retain")
+	d := flag.Duration("duration", 5*time.Second, "how long to run the workload")
+	interval := flag.Duration("interval", 5*time.Millisecond, "time between samples")
+	gogc := flag.String("gogc", "", "GC target percentage, or off; defaults to $GOGC")
+	memlimit := flag.String("memlimit", "", "soft memory limit, such as 256MB; defaults to $GOMEMLIMIT")
+	liveFlag := flag.String("live", "64MB", "bytes kept reachable by the retain workload")
+	svg := flag.String("svg", "", "also write the chart to `file`")
+	flag.Parse()
+
+	w, ok := workloads[*name]
+	if !ok {
+		log.Fatalf("unknown workload %q", *name)
+	}
+	live, err := bytesize.Parse(*liveFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *gogc != "" {
+		percent, err := parseGOGC(*gogc)
+		if err != nil {
+			log.Fatal(err)
+		}
+		debug.SetGCPercent(percent)
+	}
+	if *memlimit != "" {
+		limit, err := bytesize.Parse(*memlimit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		debug.SetMemoryLimit(limit)
+	}
+	// There is no getter for GOGC: set it and put it back. A negative
+	// memory limit leaves the limit as it is.
+	percent := debug.SetGCPercent(-1)
+	debug.SetGCPercent(percent)
+	limit := debug.SetMemoryLimit(-1)
+
+	samples := run(w, live, *d, *interval)
+
+	out := csv.NewWriter(os.Stdout)
+	writeCSV(out, samples)
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Fatal(err)
+	}
+
+	title := fmt.Sprintf("%s workload, GOGC=%s, GOMEMLIMIT=%s", *name, gogcName(percent), limitName(limit))
+	if *name == "retain" {
+		title += ", live=" + bytesize.Format(live)
+	}
+	if *svg != "" {
+		f, err := os.Create(*svg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := writeSVG(f, title, samples); err != nil {
+			log.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	summarise(os.Stderr, title, samples)
+}
+
+// run runs w for d, sampling every interval.
+func run(w workload, live int64, d, interval time.Duration) []sample {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		w(live, stop)
+		close(done)
+	}()
+	s := newSampler()
+	time.AfterFunc(d, func() { close(stop) })
+	samples := s.run(interval, stop)
+	<-done
+	return samples
+}
+
+func writeCSV(w *csv.Writer, samples []sample) {
+	w.Write([]string{"time_ms", "heap_goal", "heap_live", "heap_objects", "gc_cycles", "pauses", "pause_total_us", "pause_max_us"})
+	for _, s := range samples {
+		w.Write([]string{
+			strconv.FormatFloat(float64(s.t)/float64(time.Millisecond), 'f', 1, 64),
+			strconv.FormatUint(s.goal, 10),
+			strconv.FormatUint(s.live, 10),
+			strconv.FormatUint(s.objects, 10),
+			strconv.FormatUint(s.cycles, 10),
+			strconv.FormatUint(s.pauses, 10),
+			strconv.FormatFloat(float64(s.pauseTotal)/float64(time.Microsecond), 'f', 1, 64),
+			strconv.FormatFloat(float64(s.pauseMax)/float64(time.Microsecond), 'f', 1, 64),
+		})
+	}
+}
+
+func summarise(w io.Writer, title string, samples []sample) {
+	first, last := samples[0], samples[len(samples)-1]
+	var pauses uint64
+	var total, longest time.Duration
+	var peak uint64
+	for _, s := range samples {
+		pauses += s.pauses
+		total += s.pauseTotal
+		longest = max(longest, s.pauseMax)
+		peak = max(peak, s.objects)
+	}
+	fmt.Fprintln(w, title)
+	fmt.Fprintf(w, "GC cycles:   %d in %v\n", last.cycles-first.cycles, (last.t - first.t).Round(time.Millisecond))
+	fmt.Fprintf(w, "pauses:      %d, about %v in total, longest under %v\n", pauses, total.Round(time.Microsecond), longest)
+	fmt.Fprintf(w, "peak heap:   %d MB of objects, final goal %d MB\n", peak>>20, last.goal>>20)
+}
+
+// parseGOGC parses a GOGC value: a percentage or off.
+func parseGOGC(s string) (int, error) {
+	if strings.EqualFold(s, "off") {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid GOGC %q", s)
+	}
+	return n, nil
+}
+
+func gogcName(percent int) string {
+	if percent < 0 {
+		return "off"
+	}
+	return strconv.Itoa(percent)
+}
+
+func limitName(limit int64) string {
+	if limit == math.MaxInt64 {
+		return "off"
+	}
+	return bytesize.Format(limit)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"runtime/metrics"
+	"time"
+)
+
+// The runtime/metrics recorded in each sample.
+const (
+	heapGoal    = "/gc/heap/goal:bytes"
+	heapLive    = "/gc/heap/live:bytes"
+	heapObjects = "/memory/classes/heap/objects:bytes"
+	gcCycles    = "/gc/cycles/total:gc-cycles"
+	gcPauses    = "/sched/pauses/total/gc:seconds"
+)
+
+// sample is the state of the heap at one point in a run.
+type sample struct {
+	t time.Duration // since the start of the run
+
+	goal    uint64 // heap size at which the next cycle will finish
+	live    uint64 // heap marked live by the last cycle
+	objects uint64 // heap occupied by objects, live or not yet swept
+	cycles  uint64 // completed GC cycles
+
+	// The stop-the-world pauses since the previous sample. The
+	// runtime records pauses in a histogram, so the total and maximum
+	// are estimates to within a bucket.
+	pauses     uint64
+	pauseTotal time.Duration
+	pauseMax   time.Duration
+}
+
+// sampler reads runtime/metrics and turns them into samples.
+type sampler struct {
+	start   time.Time
+	metrics []metrics.Sample
+	counts  []uint64 // pause histogram at the previous sample
+}
+
+func newSampler() *sampler {
+	s := &sampler{start: time.Now()}
+	for _, name := range []string{heapGoal, heapLive, heapObjects, gcCycles, gcPauses} {
+		s.metrics = append(s.metrics, metrics.Sample{Name: name})
+	}
+	metrics.Read(s.metrics)
+	s.counts = append(s.counts, s.metrics[4].Value.Float64Histogram().Counts...)
+	return s
+}
+
+// sample reads the current values of the metrics.
+func (s *sampler) sample() sample {
+	metrics.Read(s.metrics)
+	h := s.metrics[4].Value.Float64Histogram()
+	n, total, longest := pauseDelta(h.Buckets, s.counts, h.Counts)
+	s.counts = append(s.counts[:0], h.Counts...)
+	return sample{
+		t:          time.Since(s.start),
+		goal:       s.metrics[0].Value.Uint64(),
+		live:       s.metrics[1].Value.Uint64(),
+		objects:    s.metrics[2].Value.Uint64(),
+		cycles:     s.metrics[3].Value.Uint64(),
+		pauses:     n,
+		pauseTotal: total,
+		pauseMax:   longest,
+	}
+}
+
+// run samples every interval until stop is closed.
+func (s *sampler) run(interval time.Duration, stop <-chan struct{}) []sample {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	samples := []sample{s.sample()}
+	for {
+		select {
+		case <-stop:
+			return append(samples, s.sample())
+		case <-t.C:
+			samples = append(samples, s.sample())
+		}
+	}
+}
+
+// pauseDelta returns the number of pauses recorded in a histogram between
+// prev and cur, with an estimate of their total and longest duration.
+// Each pause is taken to be the midpoint of its bucket; a bucket with an
+// infinite bound is taken to be its finite one.
+func pauseDelta(buckets []float64, prev, cur []uint64) (n uint64, total, longest time.Duration) {
+	for i, c := range cur {
+		d := c - prev[i]
+		if d == 0 {
+			continue
+		}
+		lo, hi := buckets[i], buckets[i+1]
+		mid := (lo + hi) / 2
+		switch {
+		case math.IsInf(lo, -1):
+			mid = hi
+		case math.IsInf(hi, 1):
+			mid, hi = lo, lo
+		}
+		n += d
+		total += time.Duration(float64(d) * mid * float64(time.Second))
+		longest = time.Duration(hi * float64(time.Second))
+	}
+	return n, total, longest
+}
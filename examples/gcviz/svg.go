@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// The dimensions of the chart, in pixels. The heap panel is above the
+// pause panel; both share the time axis.
+const (
+	chartWidth  = 800
+	heapHeight  = 300
+	pauseHeight = 100
+	marginLeft  = 70
+	marginRight = 20
+	marginTop   = 40
+	panelGap    = 40
+	marginBelow = 40
+)
+
+// series is a line in the heap panel.
+type series struct {
+	name   string
+	colour string
+	dash   bool
+	value  func(sample) uint64
+}
+
+func (hs series) dasharray() string {
+	if hs.dash {
+		return ` stroke-dasharray="4,3"`
+	}
+	return ""
+}
+
+var heapSeries = []series{
+	{"heap goal", "#d62728", true, func(s sample) uint64 { return s.goal }},
+	{"heap objects", "#2ca02c", false, func(s sample) uint64 { return s.objects }},
+	{"live heap", "#1f77b4", false, func(s sample) uint64 { return s.live }},
+}
+
+// writeSVG draws samples as a chart: heap sizes over time, with a tick
+// for each GC cycle, and below them the longest pause in each interval.
+func writeSVG(w io.Writer, title string, samples []sample) error {
+	bw := bufio.NewWriter(w)
+	width := marginLeft + chartWidth + marginRight
+	height := marginTop + heapHeight + panelGap + pauseHeight + marginBelow
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"12\">\n", width, height)
+	fmt.Fprintf(bw, "<rect width=\"%d\" height=\"%d\" fill=\"white\"/>\n", width, height)
+	fmt.Fprintf(bw, "<text x=\"%d\" y=\"20\" font-size=\"14\">", marginLeft)
+	xml.EscapeText(bw, []byte(title))
+	fmt.Fprintf(bw, "</text>\n")
+	if len(samples) == 0 {
+		fmt.Fprintf(bw, "</svg>\n")
+		return bw.Flush()
+	}
+
+	var end time.Duration
+	var top uint64
+	var longest time.Duration
+	for _, s := range samples {
+		end = max(end, s.t)
+		for _, hs := range heapSeries {
+			top = max(top, hs.value(s))
+		}
+		longest = max(longest, s.pauseMax)
+	}
+	top = (top>>20 + 1) << 20 // round up to the next MB
+	longest = max(longest, time.Microsecond)
+	x := func(t time.Duration) float64 {
+		if end == 0 {
+			return marginLeft
+		}
+		return marginLeft + float64(t)/float64(end)*chartWidth
+	}
+
+	// Heap panel.
+	heapBottom := marginTop + heapHeight
+	yHeap := func(v uint64) float64 {
+		return float64(heapBottom) - float64(v)/float64(top)*heapHeight
+	}
+	axes(bw, marginTop, heapHeight, fmt.Sprintf("%d MB", top>>20), "0")
+	for i, s := range samples[1:] {
+		// Cycles that finished within one interval share a tick.
+		if s.cycles > samples[i].cycles {
+			fmt.Fprintf(bw, "<line x1=\"%.1f\" y1=\"%d\" x2=\"%.1f\" y2=\"%d\" stroke=\"#999\"/>\n", x(s.t), heapBottom, x(s.t), heapBottom-6)
+		}
+	}
+	for _, hs := range heapSeries {
+		var points strings.Builder
+		for _, s := range samples {
+			fmt.Fprintf(&points, "%.1f,%.1f ", x(s.t), yHeap(hs.value(s)))
+		}
+		fmt.Fprintf(bw, "<polyline points=\"%s\" fill=\"none\" stroke=\"%s\"%s/>\n", strings.TrimSpace(points.String()), hs.colour, hs.dasharray())
+	}
+	for i, hs := range heapSeries {
+		lx := marginLeft + 10 + i*130
+		fmt.Fprintf(bw, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"%s\"%s/>\n", lx, marginTop+10, lx+20, marginTop+10, hs.colour, hs.dasharray())
+		fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\">%s</text>\n", lx+25, marginTop+14, hs.name)
+	}
+	fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\">GC cycles: %d</text>\n", marginLeft+10+len(heapSeries)*130, marginTop+14, samples[len(samples)-1].cycles-samples[0].cycles)
+
+	// Pause panel.
+	pauseTop := heapBottom + panelGap
+	pauseBottom := pauseTop + pauseHeight
+	axes(bw, pauseTop, pauseHeight, longest.String(), "0")
+	fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\">longest GC pause per sample</text>\n", marginLeft+10, pauseTop+14)
+	for _, s := range samples {
+		if s.pauses == 0 {
+			continue
+		}
+		h := float64(s.pauseMax) / float64(longest) * pauseHeight
+		fmt.Fprintf(bw, "<rect x=\"%.1f\" y=\"%.1f\" width=\"2\" height=\"%.1f\" fill=\"#ff7f0e\"/>\n", x(s.t)-1, float64(pauseBottom)-h, h)
+	}
+	fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">%s</text>\n", marginLeft+chartWidth, pauseBottom+20, end.Round(time.Millisecond))
+	fmt.Fprintf(bw, "<text x=\"%d\" y=\"%d\">0s</text>\n", marginLeft, pauseBottom+20)
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// axes draws the frame of a panel, labelled with its top and bottom values.
+func axes(w io.Writer, top, height int, topLabel, bottomLabel string) {
+	fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"none\" stroke=\"#333\"/>\n", marginLeft, top, chartWidth, height)
+	fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">%s</text>\n", marginLeft-5, top+4, topLabel)
+	fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">%s</text>\n", marginLeft-5, top+height+4, bottomLabel)
+}
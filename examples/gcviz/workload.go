@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// A workload allocates until stop is closed. live is the number of bytes
+// a workload with long-lived objects keeps reachable.
+type workload func(live int64, stop <-chan struct{})
+
+var workloads = map[string]workload{
+	"steady": steady,
+	"bursty": bursty,
+	"retain": retain,
+}
+
+// maxObject is the largest object a workload allocates. Objects are
+// between 0 and maxObject bytes, 8KB on average, like inuseallocs.
+const maxObject = 1 << 14
+
+// shortLived is the number of objects steady and bursty keep reachable:
+// each object is dropped soon after it is allocated.
+const shortLived = 64
+
+// steady allocates short-lived objects as fast as it can. The live heap
+// is small, so the heap goal stays at the 4MB minimum unless GOGC is
+// raised and the GC runs at a steady, high rate.
+func steady(live int64, stop <-chan struct{}) {
+	var ring [shortLived][]byte
+	for i := 0; ; i++ {
+		if i%1024 == 0 && stopped(stop) {
+			return
+		}
+		ring[i%len(ring)] = make([]byte, rand.Intn(maxObject))
+	}
+}
+
+// bursty is steady for 100ms, then idle for 200ms. The GC paces itself
+// for the bursts; during the idle periods the heap goal stays where the
+// last burst left it.
+func bursty(live int64, stop <-chan struct{}) {
+	var ring [shortLived][]byte
+	for i := 0; ; {
+		deadline := time.Now().Add(100 * time.Millisecond)
+		for ; time.Now().Before(deadline); i++ {
+			ring[i%len(ring)] = make([]byte, rand.Intn(maxObject))
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// retain grows its live set to live bytes and keeps it, replacing a
+// random object with a new one for each allocation. The objects live
+// for many cycles, so each cycle has the whole live set to mark and the
+// heap goal stays at about twice live with the default GOGC.
+func retain(live int64, stop <-chan struct{}) {
+	var objects [][]byte
+	var size int64
+	for i := 0; ; i++ {
+		if i%1024 == 0 && stopped(stop) {
+			return
+		}
+		b := make([]byte, rand.Intn(maxObject))
+		if size < live {
+			objects = append(objects, b)
+			size += int64(len(b))
+			continue
+		}
+		j := rand.Intn(len(objects))
+		size += int64(len(b) - len(objects[j]))
+		objects[j] = b
+	}
+}
+
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
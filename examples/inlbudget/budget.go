@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+// defaultBudget is the compiler's inlining budget, inlineMaxBudget in
+// cmd/compile/internal/inline. It is used unless the compiler reports a
+// different one, for example because profile guided optimisation raised
+// it for a hot function.
+const defaultBudget = 80
+
+// function is the compiler's inlining decision about a function.
+type function struct {
+	pos       gcdiag.Pos
+	name      string
+	cost      int // -1 if the compiler did not say
+	budget    int
+	inlinable bool
+	reason    string // why not, if not inlinable
+	calls     []call // calls inlined into this function
+}
+
+// call is a call site which was inlined.
+type call struct {
+	pos    gcdiag.Pos
+	callee string
+
+	// nested holds the functions inlined into callee which were
+	// inlined along with it. If there are any the call was inlined mid
+	// stack: callee is not a leaf.
+	nested []string
+}
+
+func (c call) midStack() bool { return len(c.nested) > 0 }
+
+// functions extracts the inlining decisions from the output of
+// -gcflags=-m=2, in the order the compiler reported them.
+//
+// The compiler reports each function as
+//
+//	max.go:4:6: can inline Max with cost 8 as: func(int, int) int { ... }
+//	center.go:16:6: cannot inline NewPoint: function too complex: cost 98 exceeds budget 80
+//
+// and each inlined call as "inlining call to Max". When a call to F is
+// inlined, calls inside F which F had inlined are reported again at the
+// position of the call to F.
+func functions(diags []gcdiag.Diagnostic, funcs *gcdiag.Funcs) []*function {
+	var fns []*function
+	byName := make(map[string]*function)
+	lookup := func(name string) *function {
+		fn, ok := byName[name]
+		if !ok {
+			fn = &function{name: name, cost: -1, budget: defaultBudget}
+			byName[name] = fn
+			fns = append(fns, fn)
+		}
+		return fn
+	}
+	for _, d := range diags {
+		msg := d.Message
+		switch {
+		case funcs.At(d.Pos) == "":
+			// not in a function declared in the package's own files, for
+			// example a generic function instantiated from another package.
+			continue
+		case strings.HasPrefix(msg, "can inline "):
+			rest, _, _ := strings.Cut(strings.TrimPrefix(msg, "can inline "), " as: ")
+			name, cost, _ := strings.Cut(rest, " with cost ")
+			fn := lookup(name)
+			fn.pos, fn.inlinable = d.Pos, true
+			if n, err := strconv.Atoi(cost); err == nil {
+				fn.cost = n
+			}
+		case strings.HasPrefix(msg, "cannot inline ") && !strings.HasPrefix(msg, "cannot inline call"):
+			name, reason, _ := strings.Cut(strings.TrimPrefix(msg, "cannot inline "), ": ")
+			fn := lookup(name)
+			fn.pos, fn.reason = d.Pos, reason
+			var cost, budget int
+			if _, err := fmt.Sscanf(reason, "function too complex: cost %d exceeds budget %d", &cost, &budget); err == nil {
+				fn.cost, fn.budget = cost, budget
+			}
+		case strings.HasPrefix(msg, "inlining call to "):
+			callee := strings.TrimPrefix(msg, "inlining call to ")
+			caller := lookup(funcs.At(d.Pos))
+			caller.addCall(d.Pos, callee)
+		}
+	}
+	// A budget reported for any function applies to those for which
+	// the compiler gave only a cost.
+	budget := defaultBudget
+	for _, fn := range fns {
+		if fn.budget != defaultBudget {
+			budget = fn.budget
+			break
+		}
+	}
+	for _, fn := range fns {
+		if fn.inlinable {
+			fn.budget = budget
+		}
+	}
+	return fns
+}
+
+// addCall records that callee was inlined at pos. The first callee
+// reported at a position is the one called there; others were inlined
+// into it. The compiler may report the same call more than once.
+func (fn *function) addCall(pos gcdiag.Pos, callee string) {
+	for i := range fn.calls {
+		c := &fn.calls[i]
+		if c.pos != pos {
+			continue
+		}
+		if c.callee != callee && !slices.Contains(c.nested, callee) {
+			c.nested = append(c.nested, callee)
+		}
+		return
+	}
+	fn.calls = append(fn.calls, call{pos: pos, callee: callee})
+}
+
+// suggestion returns advice for a function whose cost is within near of
+// its budget, or "" if there is none.
+func suggestion(fn *function, near int) string {
+	if fn.cost < 0 {
+		return ""
+	}
+	switch over := fn.cost - fn.budget; {
+	case !fn.inlinable && over > 0 && over <= near:
+		return fmt.Sprintf("cost %d is %d over the budget of %d; moving rarely executed code, such as error handling or a panic, into a separate function may let it be inlined",
+			fn.cost, over, fn.budget)
+	case fn.inlinable && -over <= near:
+		return fmt.Sprintf("cost %d is %d under the budget of %d; a small addition will stop it being inlined",
+			fn.cost, -over, fn.budget)
+	}
+	return ""
+}
+
+// writeReport writes a line for each function, followed by one for each
+// call inlined into it, then the suggestions for functions within near of
+// the budget.
+func writeReport(w io.Writer, fns []*function, near int) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "POSITION\tFUNCTION\tCOST\tBUDGET\tINLINE\n")
+	for _, fn := range fns {
+		if fn.pos.File == "" {
+			continue // only seen as the caller of an inlined call
+		}
+		cost := "-"
+		if fn.cost >= 0 {
+			cost = strconv.Itoa(fn.cost)
+		}
+		inline := "yes"
+		if !fn.inlinable {
+			inline = "no: " + fn.reason
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", fn.pos, fn.name, cost, fn.budget, inline)
+		for _, c := range fn.calls {
+			fmt.Fprintf(tw, "  %s\t\t\t\tcalls %s", c.pos, c.callee)
+			if c.midStack() {
+				fmt.Fprintf(tw, ", mid-stack with %s", strings.Join(c.nested, ", "))
+			}
+			fmt.Fprintln(tw)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	first := true
+	for _, fn := range fns {
+		s := suggestion(fn, near)
+		if s == "" {
+			continue
+		}
+		if first {
+			fmt.Fprintln(w)
+			first = false
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s: %s\n", fn.pos, fn.name, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+func compile(t *testing.T, pkg string) []*function {
+	t.Helper()
+	out, err := gcdiag.Compile(pkg, false, "-m=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags, err := gcdiag.Parse(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return functions(diags, new(gcdiag.Funcs))
+}
+
+func find(t *testing.T, fns []*function, name string) *function {
+	t.Helper()
+	for _, fn := range fns {
+		if fn.name == name {
+			return fn
+		}
+	}
+	t.Fatalf("%s not reported", name)
+	return nil
+}
+
+// TestMax checks examples/inl/max.go, in which Max is inlined into F and F,
+// with Max, into main.
+func TestMax(t *testing.T) {
+	fns := compile(t, "../inl")
+	max, f, main := find(t, fns, "Max"), find(t, fns, "F"), find(t, fns, "main")
+	for _, fn := range []*function{max, f, main} {
+		if !fn.inlinable || fn.cost <= 0 || fn.budget != defaultBudget {
+			t.Errorf("%s: inlinable %v, cost %d, budget %d", fn.name, fn.inlinable, fn.cost, fn.budget)
+		}
+	}
+	if max.cost >= f.cost {
+		t.Errorf("Max costs %d, no less than F which calls it, %d", max.cost, f.cost)
+	}
+	if len(f.calls) != 1 || f.calls[0].callee != "Max" || f.calls[0].midStack() {
+		t.Errorf("F: calls %+v, want a leaf call to Max", f.calls)
+	}
+	want := call{pos: gcdiag.Pos{File: "../inl/max.go", Line: 21, Col: 3}, callee: "F", nested: []string{"Max"}}
+	if len(main.calls) != 1 || !reflect.DeepEqual(main.calls[0], want) {
+		t.Errorf("main: calls %+v, want %+v", main.calls, want)
+	}
+}
+
+// TestDeadCode checks max2.go and max3.go, which replace the call to Max
+// with a constant branch. The compiler removes the dead branch before
+// costing F, so F is cheaper than in max.go, and the same in both.
+func TestDeadCode(t *testing.T) {
+	f := find(t, compile(t, "../inl"), "F")
+	f2 := find(t, compile(t, "../inl/max2.go"), "F")
+	f3 := find(t, compile(t, "../inl/max3.go"), "F")
+	if f2.cost >= f.cost {
+		t.Errorf("F costs %d in max2.go, no less than %d in max.go", f2.cost, f.cost)
+	}
+	if f2.cost != f3.cost {
+		t.Errorf("F costs %d in max2.go but %d in max3.go", f2.cost, f3.cost)
+	}
+	if len(f2.calls) != 0 {
+		t.Errorf("F in max2.go: calls %+v, want none", f2.calls)
+	}
+}
+
+// output is the -m=2 output for examples/esc, with the escape analysis
+// removed and the budget raised in NewPoint's message, as if by profile
+// guided optimisation.
+const output = `../esc/center.go:11:6: can inline Center with cost 8 as: func(*Point) { p.X = 320; p.Y = 240 }
+../esc/center.go:16:6: cannot inline NewPoint: function too complex: cost 98 exceeds budget 90
+../esc/sum.go:6:6: can inline Sum with cost 33 as: func() int { numbers := make([]int, 100); for loop; sum = <nil>; for loop; return sum }
+../esc/sum.go:20:6: cannot inline main: function too complex: cost 117 exceeds budget 90
+../esc/center.go:18:8: inlining call to Center
+../esc/center.go:19:13: inlining call to fmt.Println
+../esc/center.go:19:13: inlining call to fmt.Println
+../esc/sum.go:21:15: inlining call to Sum
+../esc/sum.go:22:13: inlining call to fmt.Println
+`
+
+func TestFunctions(t *testing.T) {
+	diags, err := gcdiag.Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fns := functions(diags, new(gcdiag.Funcs))
+	var names []string
+	for _, fn := range fns {
+		names = append(names, fn.name)
+		if fn.budget != 90 {
+			t.Errorf("%s: budget %d, want 90", fn.name, fn.budget)
+		}
+	}
+	if want := []string{"Center", "NewPoint", "Sum", "main"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %q, want %q", names, want)
+	}
+	if got := len(fns[1].calls); got != 2 {
+		t.Errorf("NewPoint: got %d calls, want 2, the repeated call to fmt.Println once", got)
+	}
+	np := fns[1]
+	if np.inlinable || np.cost != 98 || np.reason != "function too complex: cost 98 exceeds budget 90" {
+		t.Errorf("NewPoint: %+v", np)
+	}
+}
+
+func TestSuggestion(t *testing.T) {
+	for _, tt := range []struct {
+		fn   function
+		want string
+	}{
+		{function{name: "NewPoint", cost: 98, budget: 80}, "cost 98 is 18 over the budget of 80; moving rarely executed code, such as error handling or a panic, into a separate function may let it be inlined"},
+		{function{name: "main", cost: 117, budget: 80}, ""},
+		{function{name: "Info", cost: 62, budget: 80, inlinable: true}, "cost 62 is 18 under the budget of 80; a small addition will stop it being inlined"},
+		{function{name: "Max", cost: 8, budget: 80, inlinable: true}, ""},
+		{function{name: "H", cost: -1, budget: 80, reason: "unhandled op GO"}, ""},
+	} {
+		if got := suggestion(&tt.fn, 20); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.fn.name, got, tt.want)
+		}
+	}
+}
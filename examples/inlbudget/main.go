@@ -0,0 +1,59 @@
+// inlbudget explains the compiler's inlining decisions for a package.
+//
+// It compiles the package with -gcflags=-m=2 and prints, for each
+// function, its inlining cost as computed by the compiler, the budget it
+// must not exceed, and whether it can be inlined; and for each call which
+// was inlined, whether the callee's own inlined calls came with it, that
+// is, whether it was inlined mid stack. Functions whose cost is close to
+// the budget, on either side, are listed at the end with a suggestion.
+//
+//	go run ./examples/inlbudget ./examples/inl
+//	go run ./examples/inlbudget examples/inl/max2.go
+//
+// A single file may be given instead of a package, which is how max2.go
+// and max3.go, whose build constraints exclude them from the package, can
+// be examined.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("inlbudget: ")
+	near := flag.Int("near", 20, "suggest changes to functions whose cost is within `n` of the budget")
+	tests := flag.Bool("test", false, "compile the package's test files too")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: inlbudget [-near n] [-test] [package | file.go]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	pkg := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		pkg = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	out, err := gcdiag.Compile(pkg, *tests, "-m=2")
+	if err != nil {
+		log.Fatal(err)
+	}
+	diags, err := gcdiag.Parse(bytes.NewReader(out))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := writeReport(os.Stdout, functions(diags, new(gcdiag.Funcs)), *near); err != nil {
+		log.Fatal(err)
+	}
+}
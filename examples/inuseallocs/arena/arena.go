@@ -0,0 +1,53 @@
+package main
+
+// arena is a bump allocator for byte slices. It allocates memory from the
+// heap in large chunks and hands out slices of them, so that many small
+// allocations cost the garbage collector one object per chunk rather than
+// one each. The price is that a chunk stays live while any slice of it
+// is, and that nothing allocated from the arena may be used after Reset.
+type arena struct {
+	chunkSize int
+	chunks    [][]byte
+	next      int // index of the chunk being allocated from
+	off       int // offset of the free space in chunks[next]
+}
+
+func newArena(chunkSize int) *arena {
+	return &arena{chunkSize: chunkSize}
+}
+
+// Alloc returns a zeroed slice of n bytes. Its capacity is n, so that
+// appending to it cannot overwrite the slice allocated after it.
+// Allocations larger than a chunk are passed to make.
+func (a *arena) Alloc(n int) []byte {
+	if n > a.chunkSize {
+		return make([]byte, n)
+	}
+	for {
+		if a.next == len(a.chunks) {
+			a.chunks = append(a.chunks, make([]byte, a.chunkSize))
+		}
+		c := a.chunks[a.next]
+		if a.off+n <= len(c) {
+			b := c[a.off : a.off+n : a.off+n]
+			a.off += n
+			return b
+		}
+		a.next++
+		a.off = 0
+	}
+}
+
+// Reset frees everything allocated from the arena, keeping its chunks
+// for reuse. They are zeroed now, so that Alloc need not.
+func (a *arena) Reset() {
+	for i := 0; i < len(a.chunks) && i <= a.next; i++ {
+		clear(a.chunks[i])
+	}
+	a.next, a.off = 0, 0
+}
+
+// Size returns the number of bytes held by the arena's chunks.
+func (a *arena) Size() int {
+	return len(a.chunks) * a.chunkSize
+}
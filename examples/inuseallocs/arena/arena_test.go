@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+)
+
+// sizes returns n allocation sizes in [0, 2*chunkSize), so that about
+// half are passed to make.
+func sizes(seed int64, n, chunkSize int) []int {
+	r := rand.New(rand.NewSource(seed))
+	s := make([]int, n)
+	for i := range s {
+		s[i] = r.Intn(2 * chunkSize)
+	}
+	return s
+}
+
+func TestArena(t *testing.T) {
+	a := newArena(1 << 10)
+	allocs := sizes(1, 1000, 1<<10)
+	var slices [][]byte
+	for i, n := range allocs {
+		b := a.Alloc(n)
+		if len(b) != n || cap(b) != n {
+			t.Fatalf("Alloc(%d): len %d cap %d", n, len(b), cap(b))
+		}
+		for j := range b {
+			if b[j] != 0 {
+				t.Fatalf("Alloc(%d): byte %d is %d, not zero", n, j, b[j])
+			}
+			b[j] = byte(i)
+		}
+		slices = append(slices, b)
+	}
+	// No slice was overwritten by a later one.
+	for i, b := range slices {
+		for j := range b {
+			if b[j] != byte(i) {
+				t.Fatalf("slice %d: byte %d is %d, want %d", i, j, b[j], byte(i))
+			}
+		}
+	}
+
+	// The same allocations after Reset reuse the same chunks, which
+	// have been zeroed.
+	chunks := len(a.chunks)
+	a.Reset()
+	first := a.Alloc(allocs[0])
+	if allocs[0] <= a.chunkSize && allocs[0] > 0 && &first[0] != &a.chunks[0][0] {
+		t.Fatal("Alloc after Reset does not reuse the first chunk")
+	}
+	for _, n := range allocs[1:] {
+		b := a.Alloc(n)
+		for j := range b {
+			if b[j] != 0 {
+				t.Fatalf("after Reset: byte %d is %d, not zero", j, b[j])
+			}
+		}
+	}
+	if len(a.chunks) != chunks {
+		t.Fatalf("%d chunks after Reset, %d before", len(a.chunks), chunks)
+	}
+}
+
+func TestArenaAllocs(t *testing.T) {
+	a := newArena(1 << 20)
+	a.Alloc(1) // allocate the first chunk
+	allocs := testing.AllocsPerRun(1000, func() {
+		a.Alloc(64)
+	})
+	if allocs != 0 {
+		t.Fatalf("%v allocations per Alloc, want 0", allocs)
+	}
+	if got := uintptr(unsafe.Pointer(&a.Alloc(1)[0])) - uintptr(unsafe.Pointer(&a.chunks[0][0])); got != 1+1001*64 {
+		t.Fatalf("bump pointer at %d, want %d", got, 1+1001*64)
+	}
+}
@@ -0,0 +1,95 @@
+// arena compares allocating the byte slices of ../main.go with make and
+// from an arena, which turns 100000 allocations into a few hundred:
+//
+//	go run ./examples/inuseallocs/arena -mode=make -rounds=5
+//	go run ./examples/inuseallocs/arena -mode=arena -rounds=5
+//	go tool pprof -sample_index=alloc_objects mem.pprof
+//
+// Each run prints the number of allocations, the bytes allocated and in use,
+// and the number of GC cycles.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+
+	"github.com/pkg/profile"
+)
+
+var (
+	mode   = flag.String("mode", "make", "allocate byte slices with `make` or from an `arena`")
+	rounds = flag.Int("rounds", 1, "number of times to allocate count slices")
+	chunk  = flag.Int("chunk", 4<<20, "arena chunk size in bytes")
+)
+
+const count = 100000
+
+var (
+	a *arena
+	y []byte
+)
+
+func main() {
+	flag.Parse()
+	switch *mode {
+	case "make":
+	case "arena":
+		a = newArena(*chunk)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown mode %q, want make or arena\n", *mode)
+		os.Exit(2)
+	}
+	defer profile.Start(profile.MemProfile, profile.MemProfileRate(1)).Stop()
+	before := memStats()
+	for i := 0; i < *rounds; i++ {
+		y = allocate()
+	}
+	runtime.GC()
+	report(before, memStats())
+}
+
+// allocate allocates count byte slices and returns the first slice allocated.
+func allocate() []byte {
+	if a != nil {
+		// everything allocated last round, y included, is freed.
+		a.Reset()
+	}
+	var x [][]byte
+	for i := 0; i < count; i++ {
+		x = append(x, makeByteSlice())
+	}
+	return x[0]
+}
+
+// makeByteSlice returns a byte slice of a random length in the range [0, 16384).
+func makeByteSlice() []byte {
+	n := rand.Intn(1 << 14)
+	if a != nil {
+		return a.Alloc(n)
+	}
+	return make([]byte, n)
+}
+
+func memStats() runtime.MemStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m
+}
+
+// report prints the allocations made, and the GC cycles run, between
+// before and after, and the memory in use after.
+func report(before, after runtime.MemStats) {
+	fmt.Fprintf(os.Stderr, "mode: %s, rounds: %d\n", *mode, *rounds)
+	fmt.Fprintf(os.Stderr, "allocs: %d objects, %d MB\n",
+		after.Mallocs-before.Mallocs, (after.TotalAlloc-before.TotalAlloc)>>20)
+	fmt.Fprintf(os.Stderr, "inuse: %d objects, %d MB\n",
+		after.HeapObjects, after.HeapAlloc>>20)
+	fmt.Fprintf(os.Stderr, "gc: %d cycles, %.1f ms total pause\n",
+		after.NumGC-before.NumGC, float64(after.PauseTotalNs-before.PauseTotalNs)/1e6)
+	if a != nil {
+		fmt.Fprintf(os.Stderr, "arena: %d chunks, %d MB\n", len(a.chunks), a.Size()>>20)
+	}
+}
@@ -30,7 +30,7 @@ func allocate() []byte {
 
 // makeByteSlice returns a byte slice of a random length in the range [0, 16384).
 func makeByteSlice() []byte {
-	return make([]byte, rand.Intn(2^14))
+	return make([]byte, rand.Intn(1<<14))
 }
 
 // end::main[]
@@ -0,0 +1,178 @@
+package kvlog
+
+import (
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// appendLogfmt appends a record in logfmt, for example
+//
+//	time=2019-07-24T09:30:15.123Z level=info msg="request served" elapsed=1.5ms
+func appendLogfmt(b []byte, now time.Time, level Level, msg string, fields []Field) []byte {
+	b = append(b, "time="...)
+	b = now.AppendFormat(b, time.RFC3339Nano)
+	b = append(b, " level="...)
+	b = append(b, level.String()...)
+	b = append(b, " msg="...)
+	b = appendLogfmtString(b, msg)
+	for i := range fields {
+		f := &fields[i]
+		b = append(b, ' ')
+		b = append(b, f.key...)
+		b = append(b, '=')
+		switch f.kind {
+		case kindString:
+			b = appendLogfmtString(b, f.str)
+		case kindError:
+			if f.err == nil {
+				b = append(b, "<nil>"...)
+			} else {
+				b = appendLogfmtString(b, f.err.Error())
+			}
+		default:
+			b = appendValue(b, f)
+		}
+	}
+	return b
+}
+
+// appendLogfmtString appends s, quoting it if it is empty or contains
+// spaces, quotes, '=' or characters which are not printable.
+func appendLogfmtString(b []byte, s string) []byte {
+	if s == "" {
+		return append(b, `""`...)
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !strconv.IsPrint(r) {
+			return strconv.AppendQuote(b, s)
+		}
+	}
+	return append(b, s...)
+}
+
+// appendJSON appends a record as a JSON object, for example
+//
+//	{"time":"2019-07-24T09:30:15.123Z","level":"info","msg":"request served","elapsed":"1.5ms"}
+func appendJSON(b []byte, now time.Time, level Level, msg string, fields []Field) []byte {
+	b = append(b, `{"time":"`...)
+	b = now.AppendFormat(b, time.RFC3339Nano)
+	b = append(b, `","level":"`...)
+	b = append(b, level.String()...)
+	b = append(b, `","msg":`...)
+	b = appendJSONString(b, msg)
+	for i := range fields {
+		f := &fields[i]
+		b = append(b, ',')
+		b = appendJSONString(b, f.key)
+		b = append(b, ':')
+		switch f.kind {
+		case kindString:
+			b = appendJSONString(b, f.str)
+		case kindError:
+			if f.err == nil {
+				b = append(b, "null"...)
+			} else {
+				b = appendJSONString(b, f.err.Error())
+			}
+		case kindInt, kindUint, kindBool:
+			b = appendValue(b, f)
+		case kindFloat:
+			if v := math.Float64frombits(f.num); math.IsNaN(v) || math.IsInf(v, 0) {
+				// JSON has no representation for these.
+				b = append(b, '"')
+				b = appendValue(b, f)
+				b = append(b, '"')
+			} else {
+				b = appendValue(b, f)
+			}
+		default:
+			b = append(b, '"')
+			b = appendValue(b, f)
+			b = append(b, '"')
+		}
+	}
+	return append(b, '}')
+}
+
+// appendJSONString appends s as a JSON string. Invalid UTF-8 is replaced
+// with U+FFFD.
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				b = append(b, s[start:i]...)
+				b = append(b, `�`...)
+				i += size
+				start = i
+				continue
+			}
+			i += size
+			continue
+		}
+		if c >= ' ' && c != '"' && c != '\\' {
+			i++
+			continue
+		}
+		b = append(b, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, `\n`...)
+		case '\r':
+			b = append(b, `\r`...)
+		case '\t':
+			b = append(b, `\t`...)
+		default:
+			b = append(b, `\u00`...)
+			b = append(b, hex[c>>4], hex[c&0xf])
+		}
+		i++
+		start = i
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// appendValue appends the unquoted form of a non string field.
+func appendValue(b []byte, f *Field) []byte {
+	switch f.kind {
+	case kindInt:
+		return strconv.AppendInt(b, int64(f.num), 10)
+	case kindUint:
+		return strconv.AppendUint(b, f.num, 10)
+	case kindFloat:
+		return strconv.AppendFloat(b, math.Float64frombits(f.num), 'g', -1, 64)
+	case kindBool:
+		return strconv.AppendBool(b, f.num == 1)
+	case kindDuration:
+		return appendDuration(b, time.Duration(f.num))
+	case kindTime:
+		return f.time.AppendFormat(b, time.RFC3339Nano)
+	}
+	return b
+}
+
+// appendDuration appends d in the largest of ns, µs, ms or s in which it is
+// at least one, for example 1.5ms. Unlike d.String it does not allocate,
+// and it does not break long durations into hours and minutes.
+func appendDuration(b []byte, d time.Duration) []byte {
+	v, unit := float64(d), "ns"
+	switch abs := d.Abs(); {
+	case abs >= time.Second:
+		v, unit = d.Seconds(), "s"
+	case abs >= time.Millisecond:
+		v, unit = v/1e6, "ms"
+	case abs >= time.Microsecond:
+		v, unit = v/1e3, "µs"
+	}
+	b = strconv.AppendFloat(b, v, 'f', -1, 64)
+	return append(b, unit...)
+}
@@ -0,0 +1,73 @@
+package kvlog
+
+import (
+	"math"
+	"time"
+)
+
+type kind uint8
+
+const (
+	kindString kind = iota
+	kindInt
+	kindUint
+	kindFloat
+	kindBool
+	kindDuration
+	kindTime
+	kindError
+)
+
+// Field is a typed key/value pair. Fields are constructed with String, Int
+// and so on; storing the value in a typed field, rather than an
+// interface{}, avoids an allocation for each field.
+type Field struct {
+	key  string
+	kind kind
+	num  uint64 // integers, floats, bools and durations
+	str  string
+	time time.Time
+	err  error
+}
+
+func String(key, value string) Field {
+	return Field{key: key, kind: kindString, str: value}
+}
+
+func Int(key string, value int) Field {
+	return Int64(key, int64(value))
+}
+
+func Int64(key string, value int64) Field {
+	return Field{key: key, kind: kindInt, num: uint64(value)}
+}
+
+func Uint64(key string, value uint64) Field {
+	return Field{key: key, kind: kindUint, num: value}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{key: key, kind: kindFloat, num: math.Float64bits(value)}
+}
+
+func Bool(key string, value bool) Field {
+	var n uint64
+	if value {
+		n = 1
+	}
+	return Field{key: key, kind: kindBool, num: n}
+}
+
+func Duration(key string, value time.Duration) Field {
+	return Field{key: key, kind: kindDuration, num: uint64(value)}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{key: key, kind: kindTime, time: value}
+}
+
+// Err returns a field with the key "error". Formatting the error calls its
+// Error method, which may allocate.
+func Err(err error) Field {
+	return Field{key: "error", kind: kindError, err: err}
+}
@@ -0,0 +1,102 @@
+// Package kvlog is a small leveled logger which writes key/value records as
+// logfmt or JSON. It applies the lessons of examples/concat: records are
+// built with strconv style append functions into pooled buffers, and fields
+// are typed rather than interface{} values, so logging a record with the
+// common field types does not allocate.
+package kvlog
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a record.
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "debug"
+	case Info:
+		return "info"
+	case Warn:
+		return "warn"
+	case Error:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Format selects the encoding of records.
+type Format int
+
+const (
+	Logfmt Format = iota
+	JSON
+)
+
+// Logger writes records at or above its level to an io.Writer, one record
+// per line. A Logger is safe for concurrent use.
+type Logger struct {
+	level  Level
+	format Format
+	now    func() time.Time
+
+	mu  sync.Mutex // serialises writes to out
+	out io.Writer
+}
+
+// New returns a Logger which writes records of at least level to w.
+func New(w io.Writer, format Format, level Level) *Logger {
+	return &Logger{level: level, format: format, now: time.Now, out: w}
+}
+
+// Enabled reports whether records at level will be written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(msg string, fields ...Field) { l.Log(Debug, msg, fields...) }
+func (l *Logger) Info(msg string, fields ...Field)  { l.Log(Info, msg, fields...) }
+func (l *Logger) Warn(msg string, fields ...Field)  { l.Log(Warn, msg, fields...) }
+func (l *Logger) Error(msg string, fields ...Field) { l.Log(Error, msg, fields...) }
+
+// buffers holds *[]byte, not []byte, so that Put does not allocate.
+var buffers = sync.Pool{New: func() interface{} {
+	b := make([]byte, 0, 512)
+	return &b
+}}
+
+// Log writes a record with the time, level, message and fields.
+func (l *Logger) Log(level Level, msg string, fields ...Field) {
+	if !l.Enabled(level) {
+		return
+	}
+	bp := buffers.Get().(*[]byte)
+	b := (*bp)[:0]
+	switch l.format {
+	case JSON:
+		b = appendJSON(b, l.now(), level, msg, fields)
+	default:
+		b = appendLogfmt(b, l.now(), level, msg, fields)
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	l.out.Write(b)
+	l.mu.Unlock()
+
+	if cap(b) <= 64<<10 { // don't let one huge record pin memory
+		*bp = b
+		buffers.Put(bp)
+	}
+}
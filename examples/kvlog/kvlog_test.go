@@ -0,0 +1,169 @@
+package kvlog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2019, 7, 24, 9, 30, 15, 123000000, time.UTC)
+
+func newTestLogger(w io.Writer, format Format, level Level) *Logger {
+	l := New(w, format, level)
+	l.now = func() time.Time { return epoch }
+	return l
+}
+
+func TestLogfmt(t *testing.T) {
+	tests := []struct {
+		msg    string
+		fields []Field
+		want   string
+	}{
+		{"hello", nil, `time=2019-07-24T09:30:15.123Z level=info msg=hello`},
+		{"request served", []Field{
+			String("remote", "127.0.0.1:52194"),
+			String("uri", "/mandelbrot"),
+			Duration("elapsed", 1500*time.Microsecond),
+		}, `time=2019-07-24T09:30:15.123Z level=info msg="request served" remote=127.0.0.1:52194 uri=/mandelbrot elapsed=1.5ms`},
+		{"types", []Field{
+			Int("int", -7),
+			Uint64("uint", math.MaxUint64),
+			Float64("float", 0.25),
+			Bool("ok", true),
+			Time("at", epoch),
+			Duration("d", 3*time.Second/2),
+			Duration("short", 12),
+		}, `time=2019-07-24T09:30:15.123Z level=info msg=types int=-7 uint=18446744073709551615 float=0.25 ok=true at=2019-07-24T09:30:15.123Z d=1.5s short=12ns`},
+		{"quoting", []Field{
+			String("empty", ""),
+			String("space", "a b"),
+			String("eq", "a=b"),
+			String("quote", `say "hi"`),
+			String("newline", "a\nb"),
+			String("unicode", "héllo"),
+			Err(errors.New("no such file")),
+			Err(nil),
+		}, `time=2019-07-24T09:30:15.123Z level=info msg=quoting empty="" space="a b" eq="a=b" quote="say \"hi\"" newline="a\nb" unicode=héllo error="no such file" error=<nil>`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		newTestLogger(&buf, Logfmt, Info).Info(tt.msg, tt.fields...)
+		if got := buf.String(); got != tt.want+"\n" {
+			t.Errorf("%s:\n got: %s\nwant: %s", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		msg    string
+		fields []Field
+		want   string
+	}{
+		{"hello", nil, `{"time":"2019-07-24T09:30:15.123Z","level":"info","msg":"hello"}`},
+		{"types", []Field{
+			Int("int", -7),
+			Float64("float", 0.25),
+			Float64("nan", math.NaN()),
+			Bool("ok", false),
+			Time("at", epoch),
+			Duration("elapsed", 2*time.Millisecond),
+			Err(nil),
+		}, `{"time":"2019-07-24T09:30:15.123Z","level":"info","msg":"types","int":-7,"float":0.25,"nan":"NaN","ok":false,"at":"2019-07-24T09:30:15.123Z","elapsed":"2ms","error":null}`},
+		{"escaping", []Field{
+			String("quote", `say "hi"\`),
+			String("control", "a\nb\tc\x01"),
+			String("invalid", "a\xffb"),
+			String("unicode", "héllo"),
+		}, `{"time":"2019-07-24T09:30:15.123Z","level":"info","msg":"escaping","quote":"say \"hi\"\\","control":"a\nb\tc\u0001","invalid":"a�b","unicode":"héllo"}`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		newTestLogger(&buf, JSON, Info).Info(tt.msg, tt.fields...)
+		if got := buf.String(); got != tt.want+"\n" {
+			t.Errorf("%s:\n got: %s\nwant: %s", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(&buf, Logfmt, Warn)
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+	got := strings.Count(buf.String(), "\n")
+	if got != 2 {
+		t.Fatalf("wrote %d records, want 2:\n%s", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), "level=warn") || !strings.Contains(buf.String(), "level=error") {
+		t.Fatalf("missing warn or error record:\n%s", buf.String())
+	}
+}
+
+func TestZeroAllocs(t *testing.T) {
+	for _, format := range []Format{Logfmt, JSON} {
+		l := New(io.Discard, format, Info)
+		allocs := testing.AllocsPerRun(1000, func() {
+			logCommonFields(l)
+		})
+		if allocs != 0 {
+			t.Errorf("format %d: %v allocs per call, want 0", format, allocs)
+		}
+	}
+}
+
+// logCommonFields logs a record like the one mandelweb writes per request.
+func logCommonFields(l *Logger) {
+	l.Info("request served",
+		String("remote", "127.0.0.1:52194"),
+		String("uri", "/mandelbrot?zoom=4"),
+		Int("status", 200),
+		Int64("bytes", 196608),
+		Float64("ratio", 0.75),
+		Bool("cached", false),
+		Duration("elapsed", 43*time.Millisecond),
+	)
+}
+
+func BenchmarkLog(b *testing.B) {
+	for _, bb := range []struct {
+		name   string
+		format Format
+	}{
+		{"logfmt", Logfmt},
+		{"json", JSON},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			l := New(io.Discard, bb.format, Info)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				logCommonFields(l)
+			}
+		})
+	}
+}
+
+func BenchmarkLogDisabled(b *testing.B) {
+	l := New(io.Discard, Logfmt, Warn)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logCommonFields(l)
+	}
+}
+
+func BenchmarkLogParallel(b *testing.B) {
+	l := New(io.Discard, Logfmt, Info)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logCommonFields(l)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+// Package logline builds the "ID addr timestamp" log line from
+// examples/concat by appending to a caller provided buffer, in the style of
+// strconv.AppendInt and time.AppendFormat, so that a buffer can be reused
+// and formatting a line need not allocate.
+package logline
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+)
+
+// TimeFormat is the layout used by time.Time.String.
+const TimeFormat = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// AppendString appends s to dst.
+func AppendString(dst []byte, s string) []byte {
+	return append(dst, s...)
+}
+
+// AppendInt appends the decimal form of i to dst.
+func AppendInt(dst []byte, i int64) []byte {
+	return strconv.AppendInt(dst, i, 10)
+}
+
+// AppendAddr appends the form of addr returned by addr.String to dst.
+// TCP and UDP addresses are formatted without allocating.
+func AppendAddr(dst []byte, addr net.Addr) []byte {
+	var ap netip.AddrPort
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ap = a.AddrPort()
+	case *net.UDPAddr:
+		ap = a.AddrPort()
+	}
+	if !ap.IsValid() {
+		return append(dst, addr.String()...)
+	}
+	// net.IP.String prints IPv4-mapped IPv6 addresses as IPv4.
+	return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port()).AppendTo(dst)
+}
+
+// AppendTime appends t to dst in the same form as t.String, without the
+// monotonic clock reading t.String adds to times returned by time.Now.
+func AppendTime(dst []byte, t time.Time) []byte {
+	return t.AppendFormat(dst, TimeFormat)
+}
+
+// AppendLine appends the line "id addr t" to dst.
+func AppendLine(dst []byte, id string, addr net.Addr, t time.Time) []byte {
+	dst = AppendString(dst, id)
+	dst = append(dst, ' ')
+	dst = AppendAddr(dst, addr)
+	dst = append(dst, ' ')
+	return AppendTime(dst, t)
+}
+
+// Line formats log lines into a buffer which is reused between calls.
+// The zero value is ready to use. A Line is not safe for concurrent use.
+type Line struct {
+	buf []byte
+}
+
+// Format formats the line "id addr t". The result is only valid until the
+// next call to Format.
+func (l *Line) Format(id string, addr net.Addr, t time.Time) []byte {
+	l.buf = AppendLine(l.buf[:0], id, addr, t)
+	return l.buf
+}
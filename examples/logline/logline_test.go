@@ -0,0 +1,158 @@
+package logline
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The five ways examples/concat builds a log line, taking the time as an
+// argument so they can be compared against a fixed clock.
+
+func byConcatenate(id string, addr net.Addr, now time.Time) string {
+	s := id
+	s += " " + addr.String()
+	s += " " + now.String()
+	return s
+}
+
+func byFprintf(id string, addr net.Addr, now time.Time) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %v %v", id, addr, now)
+	return b.String()
+}
+
+func bySprintf(id string, addr net.Addr, now time.Time) string {
+	return fmt.Sprintf("%s %v %v", id, addr, now)
+}
+
+func byStrconv(id string, addr net.Addr, now time.Time) string {
+	b := make([]byte, 0, 40)
+	b = append(b, id...)
+	b = append(b, ' ')
+	b = append(b, addr.String()...)
+	b = append(b, ' ')
+	b = now.AppendFormat(b, "2006-01-02 15:04:05.999999999 -0700 MST")
+	return string(b)
+}
+
+func byStringsBuilder(id string, addr net.Addr, now time.Time) string {
+	var b strings.Builder
+	b.WriteString(id)
+	b.WriteString(" ")
+	b.WriteString(addr.String())
+	b.WriteString(" ")
+	b.WriteString(now.String())
+	return b.String()
+}
+
+func byAppendLine(id string, addr net.Addr, now time.Time) string {
+	return string(AppendLine(nil, id, addr, now))
+}
+
+var line Line
+
+func byLine(id string, addr net.Addr, now time.Time) string {
+	return string(line.Format(id, addr, now))
+}
+
+var approaches = []struct {
+	name string
+	fn   func(string, net.Addr, time.Time) string
+}{
+	{"Concatenate", byConcatenate},
+	{"Fprintf", byFprintf},
+	{"Sprintf", bySprintf},
+	{"Strconv", byStrconv},
+	{"StringsBuilder", byStringsBuilder},
+	{"AppendLine", byAppendLine},
+	{"Line", byLine},
+}
+
+func TestApproachesIdentical(t *testing.T) {
+	now := time.Date(2019, 7, 24, 9, 30, 15, 123456789, time.FixedZone("AEST", 10*60*60))
+	addrs := []net.Addr{
+		&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}, // 16 byte, IPv4-mapped
+		&net.TCPAddr{IP: net.IP{10, 0, 0, 1}, Port: 80},
+		&net.TCPAddr{IP: net.IPv6loopback, Port: 443},
+		&net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 9, Zone: "eth0"},
+		&net.TCPAddr{Port: 1234},
+		&net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 53},
+		&net.UnixAddr{Name: "/tmp/sock", Net: "unix"},
+	}
+	for _, addr := range addrs {
+		want := byConcatenate("9001", addr, now)
+		for _, a := range approaches {
+			if got := a.fn("9001", addr, now); got != want {
+				t.Errorf("%s(%v): want %q, got %q", a.name, addr, want, got)
+			}
+		}
+	}
+}
+
+func TestLineReuse(t *testing.T) {
+	var l Line
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}
+	now := time.Unix(0, 0).UTC()
+	first := l.Format("1", addr, now)
+	second := l.Format("2", addr, now)
+	if &first[0] != &second[0] {
+		t.Fatal("Format did not reuse its buffer")
+	}
+	if want := "2 127.0.0.1:8080 1970-01-01 00:00:00 +0000 UTC"; string(second) != want {
+		t.Fatalf("want %q, got %q", want, second)
+	}
+}
+
+func TestAppendInt(t *testing.T) {
+	if got := string(AppendInt([]byte("n="), -42)); got != "n=-42" {
+		t.Fatalf("want %q, got %q", "n=-42", got)
+	}
+}
+
+// sinks to ensure the compiler does not optimise away dead assignments.
+var (
+	Result string
+	Length int
+)
+
+func BenchmarkApproaches(b *testing.B) {
+	client, err := net.Listen("tcp", ":0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer client.Close()
+	for _, a := range approaches {
+		b.Run(a.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var r string
+			for n := 0; n < b.N; n++ {
+				// Round(0) strips the monotonic clock reading, so every
+				// approach formats the same line.
+				r = a.fn("9001", client.Addr(), time.Now().Round(0))
+			}
+			Result = r
+		})
+	}
+}
+
+// BenchmarkLine does not convert the line to a string, so it does not
+// allocate at all.
+func BenchmarkLine(b *testing.B) {
+	client, err := net.Listen("tcp", ":0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer client.Close()
+	var l Line
+	b.ReportAllocs()
+	b.ResetTimer()
+	var r int
+	for n := 0; n < b.N; n++ {
+		r += len(l.Format("9001", client.Addr(), time.Now()))
+	}
+	Length = r
+}
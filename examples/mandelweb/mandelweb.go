@@ -7,12 +7,15 @@ import (
 	"image/color"
 	"image/png"
 	"log"
+	"os"
 	"runtime"
 	"sync"
 	"time"
 
 	"net/http"
 	_ "net/http/pprof"
+
+	"github.com/grafana/high-performance-go-workshop/examples/kvlog"
 )
 
 func main() {
@@ -21,11 +24,17 @@ func main() {
 	http.ListenAndServe(":8080", logRequest(http.DefaultServeMux))
 }
 
+var logger = kvlog.New(os.Stderr, kvlog.Logfmt, kvlog.Info)
+
 func logRequest(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		start := time.Now()
 		h.ServeHTTP(w, req)
-		log.Println(req.RemoteAddr, req.RequestURI, time.Since(start))
+		logger.Info("request",
+			kvlog.String("remote", req.RemoteAddr),
+			kvlog.String("uri", req.RequestURI),
+			kvlog.Duration("elapsed", time.Since(start)),
+		)
 	})
 }
 
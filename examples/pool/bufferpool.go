@@ -0,0 +1,63 @@
+package p
+
+import (
+	"math/bits"
+	"sync"
+)
+
+const (
+	minSizeClass = 6  // 64 bytes
+	maxSizeClass = 20 // 1 MiB
+)
+
+// BufferPool is a pool of byte slices in power of two size classes.
+//
+// Storing a []byte in a sync.Pool, as fn in pool.go does, allocates on every
+// Put because the three word slice header must be boxed to fit in an
+// interface{}. BufferPool stores *[]byte instead, which fits in an
+// interface without allocating, so a steady state Get/Put cycle does not
+// allocate at all.
+//
+// The zero value is ready to use.
+type BufferPool struct {
+	classes [maxSizeClass - minSizeClass + 1]sync.Pool
+}
+
+// Get returns a buffer with a length of n and a capacity of at least n.
+// Requests larger than the largest size class are allocated directly.
+func (p *BufferPool) Get(n int) *[]byte {
+	class := sizeClass(n)
+	if class > maxSizeClass {
+		b := make([]byte, n)
+		return &b
+	}
+	if v := p.classes[class-minSizeClass].Get(); v != nil {
+		b := v.(*[]byte)
+		*b = (*b)[:n]
+		return b
+	}
+	b := make([]byte, n, 1<<class)
+	return &b
+}
+
+// Put returns b to the pool. Buffers smaller than the smallest size class
+// or larger than the largest are dropped, so one huge request does not
+// stay pinned in memory.
+func (p *BufferPool) Put(b *[]byte) {
+	c := cap(*b)
+	if c < 1<<minSizeClass || c > 1<<maxSizeClass {
+		return
+	}
+	// round down, so every buffer in a class is at least as large as the
+	// class size, even if it was not allocated by Get.
+	class := bits.Len(uint(c)) - 1
+	p.classes[class-minSizeClass].Put(b)
+}
+
+// sizeClass returns the smallest size class which can hold n bytes.
+func sizeClass(n int) int {
+	if n <= 1<<minSizeClass {
+		return minSizeClass
+	}
+	return bits.Len(uint(n - 1))
+}
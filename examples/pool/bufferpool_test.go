@@ -0,0 +1,90 @@
+package p
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSizeClass(t *testing.T) {
+	tests := []struct {
+		n, want int
+	}{
+		{0, minSizeClass},
+		{1, minSizeClass},
+		{64, minSizeClass},
+		{65, 7},
+		{4096, 12},
+		{4097, 13},
+		{1 << maxSizeClass, maxSizeClass},
+		{1<<maxSizeClass + 1, maxSizeClass + 1},
+	}
+	for _, tt := range tests {
+		if got := sizeClass(tt.n); got != tt.want {
+			t.Errorf("sizeClass(%d): want %d, got %d", tt.n, tt.want, got)
+		}
+	}
+}
+
+func TestBufferPoolNeverTooSmall(t *testing.T) {
+	var p BufferPool
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		n := r.Intn(1 << (r.Intn(maxSizeClass + 2)))
+		b := p.Get(n)
+		if len(*b) != n || cap(*b) < n {
+			t.Fatalf("Get(%d): got len %d, cap %d", n, len(*b), cap(*b))
+		}
+		p.Put(b)
+
+		// also return buffers of awkward sizes the pool did not allocate.
+		odd := make([]byte, r.Intn(1<<(maxSizeClass+1)))
+		p.Put(&odd)
+	}
+}
+
+func TestBufferPoolDropsOversized(t *testing.T) {
+	var p BufferPool
+	big := make([]byte, 1<<maxSizeClass+1)
+	p.Put(&big)
+	for i := 0; i < 10; i++ {
+		if b := p.Get(1 << maxSizeClass); &(*b)[0] == &big[0] {
+			t.Fatal("Get returned an oversized buffer")
+		}
+	}
+}
+
+func BenchmarkSyncPoolSlice(b *testing.B) {
+	pool := sync.Pool{New: func() interface{} { return make([]byte, 4096) }}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		buf := pool.Get().([]byte)
+		pool.Put(buf) // allocates, buf escapes to the heap in an interface{}
+	}
+}
+
+func BenchmarkSyncPoolSlicePointer(b *testing.B) {
+	pool := sync.Pool{New: func() interface{} {
+		buf := make([]byte, 4096)
+		return &buf
+	}}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		buf := pool.Get().(*[]byte)
+		pool.Put(buf)
+	}
+}
+
+func BenchmarkBufferPool(b *testing.B) {
+	for _, size := range []int{64, 4096, 1 << 16} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			var p BufferPool
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				buf := p.Get(size)
+				p.Put(buf)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package popcount
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// The bulk functions dispatch through these variables so that on amd64
+// the assembly versions can be swapped in when the cpu supports POPCNT.
+var (
+	countSlice = countSliceGo
+	hamming    = hammingGo
+)
+
+type bulkImplementation struct {
+	name       string
+	countSlice func(xs []uint64) int
+	hamming    func(a, b []uint64) int
+}
+
+// bulkImplementations lists the available bulk code paths, so tests and
+// benchmarks can exercise each of them.
+var bulkImplementations = []bulkImplementation{
+	{"go", countSliceGo, hammingGo},
+}
+
+// PopcountSlice returns the number of set bits in xs.
+func PopcountSlice(xs []uint64) int {
+	return countSlice(xs)
+}
+
+// PopcountBytes returns the number of set bits in b.
+func PopcountBytes(b []byte) int {
+	var n0, n1, n2, n3 int
+	for len(b) >= 32 {
+		n0 += bits.OnesCount64(binary.LittleEndian.Uint64(b[0:]))
+		n1 += bits.OnesCount64(binary.LittleEndian.Uint64(b[8:]))
+		n2 += bits.OnesCount64(binary.LittleEndian.Uint64(b[16:]))
+		n3 += bits.OnesCount64(binary.LittleEndian.Uint64(b[24:]))
+		b = b[32:]
+	}
+	for len(b) >= 8 {
+		n0 += bits.OnesCount64(binary.LittleEndian.Uint64(b))
+		b = b[8:]
+	}
+	for _, c := range b {
+		n0 += int(table8[c])
+	}
+	return n0 + n1 + n2 + n3
+}
+
+// HammingDistance returns the number of bit positions in which a and b
+// differ. It panics if a and b have different lengths.
+func HammingDistance(a, b []uint64) int {
+	if len(a) != len(b) {
+		panic("popcount: HammingDistance of slices with different lengths")
+	}
+	return hamming(a, b)
+}
+
+// countSliceGo is unrolled four times with independent accumulators so
+// the additions do not form a single dependency chain.
+func countSliceGo(xs []uint64) int {
+	var n0, n1, n2, n3 int
+	for len(xs) >= 4 {
+		n0 += bits.OnesCount64(xs[0])
+		n1 += bits.OnesCount64(xs[1])
+		n2 += bits.OnesCount64(xs[2])
+		n3 += bits.OnesCount64(xs[3])
+		xs = xs[4:]
+	}
+	for _, x := range xs {
+		n0 += bits.OnesCount64(x)
+	}
+	return n0 + n1 + n2 + n3
+}
+
+func hammingGo(a, b []uint64) int {
+	b = b[:len(a)] // eliminates the bounds checks on b below
+	var n0, n1, n2, n3 int
+	for len(a) >= 4 {
+		n0 += bits.OnesCount64(a[0] ^ b[0])
+		n1 += bits.OnesCount64(a[1] ^ b[1])
+		n2 += bits.OnesCount64(a[2] ^ b[2])
+		n3 += bits.OnesCount64(a[3] ^ b[3])
+		a, b = a[4:], b[4:]
+	}
+	for i := range a {
+		n0 += bits.OnesCount64(a[i] ^ b[i])
+	}
+	return n0 + n1 + n2 + n3
+}
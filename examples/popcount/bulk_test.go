@@ -0,0 +1,158 @@
+package popcount
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestPopcountSlice(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, impl := range bulkImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			// cover every remainder of the unrolled loops.
+			for size := 0; size < 70; size++ {
+				xs := randomInputs(size)
+				if size > 0 {
+					xs[r.Intn(size)] = ^uint64(0)
+				}
+				want := 0
+				for _, x := range xs {
+					want += reference(x)
+				}
+				if got := impl.countSlice(xs); got != want {
+					t.Fatalf("len %d: want %d, got %d", size, want, got)
+				}
+			}
+		})
+	}
+	if got := PopcountSlice(nil); got != 0 {
+		t.Fatalf("PopcountSlice(nil): want 0, got %d", got)
+	}
+}
+
+func TestPopcountBytes(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for size := 0; size < 100; size++ {
+		b := make([]byte, size)
+		r.Read(b)
+		want := 0
+		for _, c := range b {
+			want += reference(uint64(c))
+		}
+		if got := PopcountBytes(b); got != want {
+			t.Fatalf("len %d: want %d, got %d", size, want, got)
+		}
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	for _, impl := range bulkImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			for size := 0; size < 70; size++ {
+				a, b := randomPair(size)
+				for i := 0; i < size; i += 3 {
+					b[i] = a[i] // some identical words
+				}
+				want := 0
+				for i := range a {
+					want += reference(a[i] ^ b[i])
+				}
+				if size > 1 && want == 0 {
+					t.Fatalf("len %d: inputs are identical", size)
+				}
+				if got := impl.hamming(a, b); got != want {
+					t.Fatalf("len %d: want %d, got %d", size, want, got)
+				}
+				if got := impl.hamming(a, a); got != 0 {
+					t.Fatalf("len %d: distance to self: want 0, got %d", size, got)
+				}
+			}
+		})
+	}
+}
+
+// randomPair returns two different slices of n random words.
+func randomPair(n int) ([]uint64, []uint64) {
+	r := rand.New(rand.NewSource(2))
+	y := make([]uint64, n)
+	for i := range y {
+		y[i] = r.Uint64()
+	}
+	return randomInputs(n), y
+}
+
+func TestHammingDistanceLengthMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("HammingDistance: want panic")
+		}
+	}()
+	HammingDistance(make([]uint64, 2), make([]uint64, 3))
+}
+
+// bulkSizes spans working sets from well inside L1 to well beyond the last
+// level cache of most machines. Once the data no longer fits in cache,
+// throughput is limited by memory bandwidth, not by how many instructions
+// each word takes to count.
+var bulkSizes = []int{4 << 10, 32 << 10, 256 << 10, 2 << 20, 16 << 20, 128 << 20}
+
+func sizeName(n int) string {
+	if n >= 1<<20 {
+		return fmt.Sprintf("%dMiB", n>>20)
+	}
+	return fmt.Sprintf("%dKiB", n>>10)
+}
+
+func BenchmarkPopcountSlice(b *testing.B) {
+	for _, size := range bulkSizes {
+		b.Run(sizeName(size), func(b *testing.B) {
+			xs := randomInputs(size / 8)
+			for _, impl := range bulkImplementations {
+				b.Run(impl.name, func(b *testing.B) {
+					b.SetBytes(int64(size))
+					var r int
+					for n := 0; n < b.N; n++ {
+						r += impl.countSlice(xs)
+					}
+					Result = r
+				})
+			}
+		})
+	}
+}
+
+func BenchmarkPopcountBytes(b *testing.B) {
+	for _, size := range bulkSizes {
+		b.Run(sizeName(size), func(b *testing.B) {
+			buf := make([]byte, size)
+			rand.New(rand.NewSource(1)).Read(buf)
+			b.ResetTimer()
+			b.SetBytes(int64(size))
+			var r int
+			for n := 0; n < b.N; n++ {
+				r += PopcountBytes(buf)
+			}
+			Result = r
+		})
+	}
+}
+
+// BenchmarkHammingDistance reads two slices, each of the given size.
+func BenchmarkHammingDistance(b *testing.B) {
+	for _, size := range bulkSizes {
+		b.Run(sizeName(size), func(b *testing.B) {
+			x, y := randomPair(size / 8)
+			for _, impl := range bulkImplementations {
+				b.Run(impl.name, func(b *testing.B) {
+					b.SetBytes(int64(2 * size))
+					var r int
+					for n := 0; n < b.N; n++ {
+						r += impl.hamming(x, y)
+					}
+					Result = r
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package popcount
+
+// hasPOPCNT reports whether the cpu supports the POPCNT instruction.
+var hasPOPCNT = cpuidECX1()&(1<<23) != 0 // CPUID.01H:ECX.POPCNT
+
+func init() {
+	if hasPOPCNT {
+		Implementations = append(Implementations, Implementation{"POPCNT", POPCNT})
+		countSlice = countSliceAsm
+		hamming = hammingAsm
+		bulkImplementations = append(bulkImplementations, bulkImplementation{"asm", countSliceAsm, hammingAsm})
+	}
+}
+
+// POPCNT counts bits with a hand written POPCNT instruction. Unlike
+// MathBits, the call cannot be inlined. It must only be called on cpus which
+// support POPCNT; see Implementations.
+func POPCNT(x uint64) int
+
+// countSliceAsm is the POPCNT version of countSliceGo.
+func countSliceAsm(xs []uint64) int
+
+// hammingAsm is the POPCNT version of hammingGo. a and b must have the
+// same length.
+func hammingAsm(a, b []uint64) int
+
+// cpuidECX1 returns the ECX register after executing CPUID with EAX=1.
+func cpuidECX1() uint32
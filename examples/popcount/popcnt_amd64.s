@@ -0,0 +1,88 @@
+#include "textflag.h"
+
+// func POPCNT(x uint64) int
+TEXT ·POPCNT(SB),NOSPLIT,$0-16
+	POPCNTQ x+0(FP), AX
+	MOVQ AX, ret+8(FP)
+	RET
+
+// func countSliceAsm(xs []uint64) int
+TEXT ·countSliceAsm(SB),NOSPLIT,$0-32
+	MOVQ xs_base+0(FP), SI
+	MOVQ xs_len+8(FP), CX
+	XORQ AX, AX
+	XORQ DX, DX
+
+loop4:
+	CMPQ CX, $4
+	JB   tail
+	POPCNTQ 0(SI), R8
+	POPCNTQ 8(SI), R9
+	POPCNTQ 16(SI), R10
+	POPCNTQ 24(SI), R11
+	ADDQ R8, AX
+	ADDQ R9, DX
+	ADDQ R10, AX
+	ADDQ R11, DX
+	ADDQ $32, SI
+	SUBQ $4, CX
+	JMP  loop4
+
+tail:
+	TESTQ CX, CX
+	JZ    done
+	POPCNTQ 0(SI), R8
+	ADDQ R8, AX
+	ADDQ $8, SI
+	DECQ CX
+	JMP  tail
+
+done:
+	ADDQ DX, AX
+	MOVQ AX, ret+24(FP)
+	RET
+
+// func hammingAsm(a, b []uint64) int
+TEXT ·hammingAsm(SB),NOSPLIT,$0-56
+	MOVQ a_base+0(FP), SI
+	MOVQ a_len+8(FP), CX
+	MOVQ b_base+24(FP), DI
+	XORQ AX, AX
+	XORQ DX, DX
+
+loop2:
+	CMPQ CX, $2
+	JB   tail
+	MOVQ 0(SI), R8
+	MOVQ 8(SI), R9
+	XORQ 0(DI), R8
+	XORQ 8(DI), R9
+	POPCNTQ R8, R8
+	POPCNTQ R9, R9
+	ADDQ R8, AX
+	ADDQ R9, DX
+	ADDQ $16, SI
+	ADDQ $16, DI
+	SUBQ $2, CX
+	JMP  loop2
+
+tail:
+	TESTQ CX, CX
+	JZ    done
+	MOVQ 0(SI), R8
+	XORQ 0(DI), R8
+	POPCNTQ R8, R8
+	ADDQ R8, AX
+
+done:
+	ADDQ DX, AX
+	MOVQ AX, ret+48(FP)
+	RET
+
+// func cpuidECX1() uint32
+TEXT ·cpuidECX1(SB),NOSPLIT,$0-4
+	MOVL $1, AX
+	XORL CX, CX
+	CPUID
+	MOVL CX, ret+0(FP)
+	RET
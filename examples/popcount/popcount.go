@@ -0,0 +1,84 @@
+// Package popcount collects several ways of counting the set bits in a
+// uint64 so they can be compared side by side.
+package popcount
+
+import "math/bits"
+
+// Implementation is a named population count function.
+type Implementation struct {
+	Name  string
+	Count func(x uint64) int
+}
+
+// Implementations lists every population count function in this package.
+// On amd64 cpus which support it, POPCNT is appended by an init function.
+var Implementations = []Implementation{
+	{"SWAR", SWAR},
+	{"Kernighan", Kernighan},
+	{"Table8", Table8},
+	{"Table16", Table16},
+	{"MathBits", MathBits},
+}
+
+const m1 = 0x5555555555555555
+const m2 = 0x3333333333333333
+const m4 = 0x0f0f0f0f0f0f0f0f
+const h01 = 0x0101010101010101
+
+// SWAR counts bits in parallel within the register, summing adjacent
+// pairs, then nibbles, then bytes, and finally gathering the byte counts
+// with a multiply. This is the popcnt function from the benchmarking
+// chapter.
+func SWAR(x uint64) int {
+	x -= (x >> 1) & m1
+	x = (x & m2) + ((x >> 2) & m2)
+	x = (x + (x >> 4)) & m4
+	return int((x * h01) >> 56)
+}
+
+// Kernighan clears the lowest set bit until none remain, so it runs in
+// time proportional to the number of set bits.
+func Kernighan(x uint64) int {
+	var n int
+	for x != 0 {
+		x &= x - 1
+		n++
+	}
+	return n
+}
+
+var (
+	table8  [1 << 8]uint8
+	table16 [1 << 16]uint8
+)
+
+func init() {
+	for i := range table8 {
+		table8[i] = table8[i/2] + uint8(i&1)
+	}
+	for i := range table16 {
+		table16[i] = table8[i&0xff] + table8[i>>8]
+	}
+}
+
+// Table8 sums the counts of each byte from a 256 entry lookup table.
+func Table8(x uint64) int {
+	return int(table8[x&0xff] + table8[x>>8&0xff] +
+		table8[x>>16&0xff] + table8[x>>24&0xff] +
+		table8[x>>32&0xff] + table8[x>>40&0xff] +
+		table8[x>>48&0xff] + table8[x>>56])
+}
+
+// Table16 sums the counts of each 16 bit word from a 64KiB lookup table.
+// It does half the lookups of Table8, but its table does not fit in the
+// L1 cache of many cpus.
+func Table16(x uint64) int {
+	return int(table16[x&0xffff] + table16[x>>16&0xffff] +
+		table16[x>>32&0xffff] + table16[x>>48])
+}
+
+// MathBits calls bits.OnesCount64, which the compiler replaces with a
+// POPCNT instruction where the cpu supports it.
+func MathBits(x uint64) int {
+	return bits.OnesCount64(x)
+}
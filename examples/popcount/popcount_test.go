@@ -0,0 +1,119 @@
+package popcount
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// reference counts bits one at a time.
+func reference(x uint64) int {
+	var n int
+	for i := 0; i < 64; i++ {
+		n += int(x >> i & 1)
+	}
+	return n
+}
+
+func edgeCases() []uint64 {
+	xs := []uint64{
+		0,
+		1,
+		^uint64(0),
+		^uint64(0) >> 1,
+		1 << 63,
+		0x5555555555555555,
+		0xaaaaaaaaaaaaaaaa,
+		0x00000000ffffffff,
+		0xffffffff00000000,
+		0x0123456789abcdef,
+	}
+	for i := 0; i < 64; i++ {
+		xs = append(xs, 1<<i, ^uint64(0)<<i, ^(uint64(1) << i))
+	}
+	return xs
+}
+
+func TestImplementations(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	inputs := edgeCases()
+	for i := 0; i < 10000; i++ {
+		inputs = append(inputs, r.Uint64())
+	}
+	for _, impl := range Implementations {
+		t.Run(impl.Name, func(t *testing.T) {
+			for _, x := range inputs {
+				if got, want := impl.Count(x), reference(x); got != want {
+					t.Fatalf("%s(%#x): want %d, got %d", impl.Name, x, want, got)
+				}
+			}
+		})
+	}
+}
+
+// sink to ensure the compiler does not optimise away dead assignments.
+var Result int
+
+// The benchmarks call each implementation through a function value, so
+// none of them are inlined; this puts the pure Go versions on an equal
+// footing with POPCNT, which can never be inlined.
+
+// BenchmarkSequential counts the bits of the loop counter. Consecutive
+// inputs have few, slowly changing, set bits, which flatters Kernighan.
+func BenchmarkSequential(b *testing.B) {
+	for _, impl := range Implementations {
+		b.Run(impl.Name, func(b *testing.B) {
+			count := impl.Count
+			var r int
+			for n := 0; n < b.N; n++ {
+				r += count(uint64(n))
+			}
+			Result = r
+		})
+	}
+}
+
+// randomInputs returns n random uint64s, generated before the timer starts
+// so the benchmark does not measure the random number generator.
+func randomInputs(n int) []uint64 {
+	r := rand.New(rand.NewSource(1))
+	xs := make([]uint64, n)
+	for i := range xs {
+		xs[i] = r.Uint64()
+	}
+	return xs
+}
+
+// BenchmarkRandom counts the bits of pregenerated random numbers.
+func BenchmarkRandom(b *testing.B) {
+	const size = 1 << 12 // small enough to stay in L1
+	xs := randomInputs(size)
+	for _, impl := range Implementations {
+		b.Run(impl.Name, func(b *testing.B) {
+			count := impl.Count
+			var r int
+			for n := 0; n < b.N; n++ {
+				r += count(xs[n&(size-1)])
+			}
+			Result = r
+		})
+	}
+}
+
+// BenchmarkBulk counts the bits of a whole slice each iteration and reports
+// throughput.
+func BenchmarkBulk(b *testing.B) {
+	xs := randomInputs(1 << 12)
+	for _, impl := range Implementations {
+		b.Run(impl.Name, func(b *testing.B) {
+			count := impl.Count
+			b.SetBytes(int64(len(xs) * 8))
+			var r int
+			for n := 0; n < b.N; n++ {
+				for _, x := range xs {
+					r += count(x)
+				}
+			}
+			Result = r
+		})
+	}
+}
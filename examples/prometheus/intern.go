@@ -0,0 +1,82 @@
+package model
+
+import "sync"
+
+// Interner deduplicates strings. Label names and values repeat across
+// millions of series, so storing one copy of each string, rather than one
+// copy per LabelSet, dramatically reduces the size of the live heap.
+//
+// The zero value is ready to use. An Interner is not safe for concurrent
+// use; see SyncInterner.
+type Interner struct {
+	m map[string]string
+}
+
+// Intern returns a string equal to s, sharing storage with any previous
+// string equal to s passed to Intern. The first time a string is seen it
+// is copied, so the table never pins a larger buffer s may be a slice of.
+func (in *Interner) Intern(s string) string {
+	if is, ok := in.m[s]; ok {
+		return is
+	}
+	if in.m == nil {
+		in.m = make(map[string]string)
+	}
+	s = string([]byte(s))
+	in.m[s] = s
+	return s
+}
+
+// Len returns the number of distinct strings held by the Interner.
+func (in *Interner) Len() int { return len(in.m) }
+
+// LabelSet returns a copy of ls whose names and values are interned.
+func (in *Interner) LabelSet(ls LabelSet) LabelSet {
+	return internLabelSet(ls, in.Intern)
+}
+
+// SyncInterner is an Interner which is safe for concurrent use by
+// multiple goroutines. Lookups of already interned strings, the common
+// case, only take a read lock.
+type SyncInterner struct {
+	mu sync.RWMutex
+	in Interner
+}
+
+// Intern returns a string equal to s, sharing storage with any previous
+// string equal to s passed to Intern.
+func (si *SyncInterner) Intern(s string) string {
+	si.mu.RLock()
+	is, ok := si.in.m[s]
+	si.mu.RUnlock()
+	if ok {
+		return is
+	}
+	si.mu.Lock()
+	is = si.in.Intern(s)
+	si.mu.Unlock()
+	return is
+}
+
+// Len returns the number of distinct strings held by the SyncInterner.
+func (si *SyncInterner) Len() int {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+	return si.in.Len()
+}
+
+// LabelSet returns a copy of ls whose names and values are interned.
+func (si *SyncInterner) LabelSet(ls LabelSet) LabelSet {
+	return internLabelSet(ls, si.Intern)
+}
+
+func internLabelSet(ls LabelSet, intern func(string) string) LabelSet {
+	if ls == nil {
+		return nil
+	}
+	out := make(LabelSet, len(ls))
+	for name, value := range ls {
+		out[intern(name)] = intern(value)
+	}
+	return out
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+// data returns the address of the bytes backing s.
+func data(s string) *byte {
+	return unsafe.StringData(s)
+}
+
+func TestInterner(t *testing.T) {
+	var in Interner
+	s := strconv.Itoa(12345)
+	a := in.Intern(s)
+	b := in.Intern(strconv.Itoa(12345))
+	if a != b || data(a) != data(b) {
+		t.Fatalf("Intern: want shared storage for %q, got %x and %x", a, data(a), data(b))
+	}
+	if data(a) == data(s) {
+		t.Fatalf("Intern: first string should be copied")
+	}
+	if got := in.Intern("other"); got != "other" {
+		t.Fatalf("Intern: want %q, got %q", "other", got)
+	}
+	if got := in.Len(); got != 2 {
+		t.Fatalf("Len: want 2, got %d", got)
+	}
+}
+
+func TestInternerLabelSet(t *testing.T) {
+	var in Interner
+	series := syntheticSeries(100)
+	first := make(map[string]string)
+	for _, s := range series {
+		ls, err := ParseLabelSet(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := in.LabelSet(ls)
+		if !reflect.DeepEqual(got, ls) {
+			t.Fatalf("LabelSet: want %v, got %v", ls, got)
+		}
+		for name, value := range got {
+			if prev, ok := first[value]; ok && data(prev) != data(value) {
+				t.Fatalf("value %q of label %q was not deduplicated", value, name)
+			}
+			first[value] = value
+		}
+	}
+	if got := in.LabelSet(nil); got != nil {
+		t.Fatalf("LabelSet(nil): want nil, got %v", got)
+	}
+}
+
+func TestSyncInterner(t *testing.T) {
+	var si SyncInterner
+	const goroutines = 8
+	results := make([][]string, goroutines)
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		g := g
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				results[g] = append(results[g], si.Intern(strconv.Itoa(i%100)))
+			}
+		}()
+	}
+	wg.Wait()
+	if got := si.Len(); got != 100 {
+		t.Fatalf("Len: want 100, got %d", got)
+	}
+	for g := 1; g < goroutines; g++ {
+		for i := range results[g] {
+			if data(results[g][i]) != data(results[0][i]) {
+				t.Fatalf("goroutine %d: %q was not deduplicated", g, results[g][i])
+			}
+		}
+	}
+}
+
+// syntheticSeries returns n series in LabelSet.String form with the label
+// cardinality of a modest Kubernetes cluster: a handful of clusters and
+// namespaces, tens of jobs and metric names, and hundreds of instances.
+func syntheticSeries(n int) []string {
+	r := rand.New(rand.NewSource(1))
+	series := make([]string, n)
+	for i := range series {
+		ls := LabelSet{
+			"__name__":  fmt.Sprintf("metric_%d_total", r.Intn(50)),
+			"cluster":   fmt.Sprintf("prod-%d", r.Intn(3)),
+			"namespace": fmt.Sprintf("namespace-%d", r.Intn(10)),
+			"job":       fmt.Sprintf("namespace-%d/job-%d", r.Intn(10), r.Intn(20)),
+			"instance":  fmt.Sprintf("10.0.%d.%d:9090", r.Intn(4), r.Intn(100)),
+		}
+		series[i] = ls.String()
+	}
+	return series
+}
+
+// sinks to ensure the compiler does not optimise away dead assignments.
+var (
+	Series []LabelSet
+	Result string
+)
+
+// benchmarkSeries parses a synthetic series set, retaining every LabelSet,
+// and reports the live heap per series. Compare the inuse_space profiles
+// of the plain and interned variants with
+//
+//	go test -run=^$ -bench=Series/ -memprofile=mem.prof
+//	go tool pprof -sample_index=inuse_space mem.prof
+func benchmarkSeries(b *testing.B, intern func(LabelSet) LabelSet) {
+	const count = 100000
+	input := syntheticSeries(count)
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ReportAllocs()
+	b.ResetTimer()
+	var kept []LabelSet
+	for n := 0; n < b.N; n++ {
+		kept = make([]LabelSet, len(input))
+		for i, s := range input {
+			// copy the input, as if it had been read from the network, so
+			// the parsed strings do not share storage with the baseline.
+			ls, err := ParseLabelSet(string([]byte(s)))
+			if err != nil {
+				b.Fatal(err)
+			}
+			kept[i] = intern(ls)
+		}
+	}
+	b.StopTimer()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(intern) // the interning table is part of the cost.
+	// HeapAlloc may have fallen if a GC ran after before was read.
+	b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/count, "live-B/series")
+	Series = kept
+}
+
+func BenchmarkSeries(b *testing.B) {
+	b.Run("plain", func(b *testing.B) {
+		benchmarkSeries(b, func(ls LabelSet) LabelSet { return ls })
+	})
+	b.Run("interned", func(b *testing.B) {
+		var in Interner
+		benchmarkSeries(b, in.LabelSet)
+	})
+	b.Run("sync-interned", func(b *testing.B) {
+		var si SyncInterner
+		benchmarkSeries(b, si.LabelSet)
+	})
+}
+
+func BenchmarkSyncInternerParallel(b *testing.B) {
+	var si SyncInterner
+	values := make([]string, 1000)
+	for i := range values {
+		values[i] = strconv.Itoa(i)
+	}
+	var r string
+	var mu sync.Mutex
+	b.RunParallel(func(pb *testing.PB) {
+		var s string
+		i := 0
+		for pb.Next() {
+			s = si.Intern(values[i%len(values)])
+			i++
+		}
+		mu.Lock()
+		r = s
+		mu.Unlock()
+	})
+	Result = r
+}
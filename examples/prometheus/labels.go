@@ -0,0 +1,44 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// IsValidLabelName reports whether name matches the Prometheus label name
+// grammar, [a-zA-Z_][a-zA-Z0-9_]*.
+func IsValidLabelName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isLabelNameByte(name[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLabelNameByte(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' && !first
+}
+
+// Validate checks that every label name in l is valid and that every label
+// value is valid UTF-8.
+func (l LabelSet) Validate() error {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names) // report the same error on every call.
+	for _, name := range names {
+		if !IsValidLabelName(name) {
+			return fmt.Errorf("invalid name %q", name)
+		}
+		if !utf8.ValidString(l[name]) {
+			return fmt.Errorf("invalid value %q for label %q", l[name], name)
+		}
+	}
+	return nil
+}
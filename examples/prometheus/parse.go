@@ -0,0 +1,120 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ParseLabelSet parses the output of LabelSet.String back into a LabelSet.
+// Label values are quoted Go strings, so escaped quotes, backslashes,
+// newlines and any other escapes produced by %q are understood.
+// Whitespace between tokens and a trailing comma are permitted.
+func ParseLabelSet(s string) (LabelSet, error) {
+	p := parser{s: s}
+	p.skipSpace()
+	if !p.consume('{') {
+		return nil, p.errorf("expected '{'")
+	}
+	ls := LabelSet{}
+	for {
+		p.skipSpace()
+		if p.consume('}') {
+			break
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume('=') {
+			return nil, p.errorf("expected '=' after label %q", name)
+		}
+		p.skipSpace()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := ls[name]; ok {
+			return nil, fmt.Errorf("duplicate label %q", name)
+		}
+		ls[name] = value
+		p.skipSpace()
+		if p.consume('}') {
+			break
+		}
+		if !p.consume(',') {
+			return nil, p.errorf("expected ',' or '}'")
+		}
+	}
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return nil, p.errorf("unexpected trailing data")
+	}
+	return ls, nil
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("parse %q at offset %d: %s", p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// name reads a label name matching the Prometheus label name grammar.
+func (p *parser) name() (string, error) {
+	start := p.pos
+	for p.pos < len(p.s) && isLabelNameByte(p.s[p.pos], p.pos == start) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected label name")
+	}
+	return p.s[start:p.pos], nil
+}
+
+// value reads a double quoted, possibly escaped, label value.
+func (p *parser) value() (string, error) {
+	start := p.pos
+	if !p.consume('"') {
+		return "", p.errorf("expected '\"'")
+	}
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '"':
+			p.pos++
+			v, err := strconv.Unquote(p.s[start:p.pos])
+			if err != nil {
+				p.pos = start
+				return "", p.errorf("invalid label value: %v", err)
+			}
+			return v, nil
+		default:
+			p.pos++
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated label value")
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelSet(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    LabelSet
+		wantErr bool
+	}{
+		"empty":           {input: `{}`, want: LabelSet{}},
+		"single":          {input: `{foo="bar"}`, want: LabelSet{"foo": "bar"}},
+		"multiple":        {input: `{abc="prometheus", foo="bar", foo2="bar"}`, want: LabelSet{"abc": "prometheus", "foo": "bar", "foo2": "bar"}},
+		"no spaces":       {input: `{a="1",b="2"}`, want: LabelSet{"a": "1", "b": "2"}},
+		"trailing comma":  {input: `{a="1", }`, want: LabelSet{"a": "1"}},
+		"escaped quote":   {input: `{a="say \"hi\""}`, want: LabelSet{"a": `say "hi"`}},
+		"backslash":       {input: `{path="C:\\temp"}`, want: LabelSet{"path": `C:\temp`}},
+		"newline":         {input: `{msg="one\ntwo"}`, want: LabelSet{"msg": "one\ntwo"}},
+		"empty value":     {input: `{a=""}`, want: LabelSet{"a": ""}},
+		"unicode":         {input: `{city="Zürich"}`, want: LabelSet{"city": "Zürich"}},
+		"missing brace":   {input: `foo="bar"}`, wantErr: true},
+		"unterminated":    {input: `{foo="bar}`, wantErr: true},
+		"missing close":   {input: `{foo="bar"`, wantErr: true},
+		"missing equals":  {input: `{foo"bar"}`, wantErr: true},
+		"unquoted value":  {input: `{foo=bar}`, wantErr: true},
+		"invalid name":    {input: `{0foo="bar"}`, wantErr: true},
+		"duplicate":       {input: `{foo="a", foo="b"}`, wantErr: true},
+		"bad escape":      {input: `{foo="\q"}`, wantErr: true},
+		"trailing data":   {input: `{foo="bar"} x`, wantErr: true},
+		"missing comma":   {input: `{a="1" b="2"}`, wantErr: true},
+		"dangling escape": {input: `{a="\`, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseLabelSet(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParseLabelSet(%q): want error, got %v", tc.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLabelSet(%q): %v", tc.input, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("ParseLabelSet(%q): want %v, got %v", tc.input, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLabelSet_Validate(t *testing.T) {
+	tests := map[string]struct {
+		input   LabelSet
+		wantErr bool
+	}{
+		"nil":              {input: nil},
+		"valid":            {input: LabelSet{"__name__": "up", "job": "api", "Job_2": ""}},
+		"empty name":       {input: LabelSet{"": "x"}, wantErr: true},
+		"leading digit":    {input: LabelSet{"1abc": "x"}, wantErr: true},
+		"dash":             {input: LabelSet{"foo-bar": "x"}, wantErr: true},
+		"unicode name":     {input: LabelSet{"zürich": "x"}, wantErr: true},
+		"invalid utf8":     {input: LabelSet{"foo": "\xff"}, wantErr: true},
+		"utf8 value":       {input: LabelSet{"foo": "日本"}},
+		"newline in value": {input: LabelSet{"foo": "a\nb"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.input.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Validate(%v): want error: %v, got: %v", tc.input, tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func FuzzLabelSetRoundTrip(f *testing.F) {
+	f.Add("foo", "bar", "job", "api")
+	f.Add("a", `say "hi"`, "b", `C:\temp`)
+	f.Add("msg", "one\ntwo", "_", "")
+	f.Add("x", "\x00\t\u2028", "y", "日本")
+	f.Fuzz(func(t *testing.T, n1, v1, n2, v2 string) {
+		ls := LabelSet{n1: v1, n2: v2}
+		if ls.Validate() != nil {
+			t.Skip()
+		}
+		s := ls.String()
+		got, err := ParseLabelSet(s)
+		if err != nil {
+			t.Fatalf("ParseLabelSet(%q): %v", s, err)
+		}
+		if !reflect.DeepEqual(got, ls) {
+			t.Fatalf("ParseLabelSet(%q): want %v, got %v", s, ls, got)
+		}
+	})
+}
+
+func FuzzParseLabelSet(f *testing.F) {
+	f.Add(`{}`)
+	f.Add(`{foo="bar"}`)
+	f.Add(`{a="say \"hi\"", b="C:\\temp", c="one\ntwo"}`)
+	f.Add(`{a="1",}`)
+	f.Fuzz(func(t *testing.T, s string) {
+		ls, err := ParseLabelSet(s)
+		if err != nil {
+			return
+		}
+		// anything that parses must print and parse back to the same set.
+		got, err := ParseLabelSet(ls.String())
+		if err != nil {
+			t.Fatalf("ParseLabelSet(%q): %v", ls.String(), err)
+		}
+		if !reflect.DeepEqual(got, ls) {
+			t.Fatalf("round trip of %q: want %v, got %v", s, ls, got)
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+// note is a message from the prove or nilcheck pass about a column of a
+// source line.
+type note struct {
+	col int
+	msg string
+}
+
+func (n note) String() string { return fmt.Sprintf("col %d: %s", n.col, n.msg) }
+
+// notes holds the notes for each line of each file.
+type notes map[string]map[int][]note
+
+// isNote reports whether msg is from the prove pass, such as
+//
+//	Proved Less32
+//	Disproved Eq64
+//	Induction variable: limits [0,?), increment 1
+//
+// or the nilcheck pass, "removed nil check" or "generated nil check".
+func isNote(msg string) bool {
+	return strings.HasPrefix(msg, "Proved ") ||
+		strings.HasPrefix(msg, "Disproved ") ||
+		strings.HasPrefix(msg, "Induction variable") ||
+		strings.HasSuffix(msg, " nil check")
+}
+
+// collect groups the prove and nilcheck messages in diags by file and
+// line. A message repeated at the same position, as happens when a
+// function is inlined more than once, is kept once. Messages which are not
+// in a function declared in the package's own files, such as those about
+// standard library code inlined into it, are left out.
+func collect(diags []gcdiag.Diagnostic, funcs *gcdiag.Funcs) notes {
+	ns := make(notes)
+	for _, d := range diags {
+		if !isNote(d.Message) || funcs.At(d.Pos) == "" {
+			continue
+		}
+		lines, ok := ns[d.Pos.File]
+		if !ok {
+			lines = make(map[int][]note)
+			ns[d.Pos.File] = lines
+		}
+		n := note{col: d.Pos.Col, msg: d.Message}
+		if !slices.Contains(lines[d.Pos.Line], n) {
+			lines[d.Pos.Line] = append(lines[d.Pos.Line], n)
+		}
+	}
+	return ns
+}
+
+// files returns the names of the files with notes, sorted.
+func (ns notes) files() []string {
+	var files []string
+	for f := range ns {
+		files = append(files, f)
+	}
+	slices.Sort(files)
+	return files
+}
+
+// annotate returns src with the notes for each line appended to it as a
+// comment. Lines are neither added nor removed, so the copy compiles, and
+// its line numbers match the original's. The notes for a line which ends
+// inside a raw string or a block comment are left out, since a comment
+// appended there would become part of the string or comment.
+func annotate(src []byte, lines map[int][]note) []byte {
+	open := openLines(src)
+	var b strings.Builder
+	for i, line := range strings.SplitAfter(string(src), "\n") {
+		ns := lines[i+1]
+		if len(ns) == 0 || open[i+1] {
+			b.WriteString(line)
+			continue
+		}
+		text, nl := strings.CutSuffix(line, "\n")
+		b.WriteString(text)
+		b.WriteString(" // ")
+		b.WriteString(join(ns))
+		if nl {
+			b.WriteByte('\n')
+		}
+	}
+	return []byte(b.String())
+}
+
+// openLines returns the numbers of the lines of src which end inside a
+// raw string literal or a block comment.
+func openLines(src []byte) map[int]bool {
+	open := make(map[int]bool)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if (tok == token.STRING || tok == token.COMMENT) && strings.Contains(lit, "\n") {
+			start := fset.Position(pos).Line
+			for l := start; l < start+strings.Count(lit, "\n"); l++ {
+				open[l] = true
+			}
+		}
+	}
+	return open
+}
+
+func join(ns []note) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = n.String()
+	}
+	return strings.Join(s, "; ")
+}
+
+// writeLines writes each line with notes, in the form
+//
+//	prove/foo.go:5: if x > 3 { // <2> // col 10: Proved Less32
+func writeLines(w io.Writer, ns notes, funcs *gcdiag.Funcs) error {
+	for _, file := range ns.files() {
+		lines := ns[file]
+		var nums []int
+		for n := range lines {
+			nums = append(nums, n)
+		}
+		slices.Sort(nums)
+		for _, n := range nums {
+			src := strings.TrimSpace(funcs.Line(gcdiag.Pos{File: file, Line: n}))
+			if _, err := fmt.Fprintf(w, "%s:%d: %s // %s\n", file, n, src, join(lines[n])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeFiles writes an annotated copy of each file with notes to dir,
+// returning the names of the copies. The copies keep their paths relative
+// to the directory which contains all the files, so files with the same
+// name in different packages do not overwrite each other.
+func writeFiles(dir string, ns notes) ([]string, error) {
+	files := ns.files()
+	root, err := commonDir(files)
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return written, err
+		}
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return written, err
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil {
+			return written, err
+		}
+		name := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(name, annotate(src, ns[file]), 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, name)
+	}
+	return written, nil
+}
+
+// commonDir returns the absolute path of the deepest directory which
+// contains all of files.
+func commonDir(files []string) (string, error) {
+	var root string
+	for i, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return "", err
+		}
+		dir := filepath.Dir(abs)
+		if i == 0 {
+			root = dir
+			continue
+		}
+		for root != filepath.Dir(root) && !within(dir, root) {
+			root = filepath.Dir(root)
+		}
+	}
+	return root, nil
+}
+
+// within reports whether dir is root or a directory below it.
+func within(dir, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
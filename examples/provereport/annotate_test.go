@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+func TestCollect(t *testing.T) {
+	const output = `# example.com/p
+p.go:5:10: Proved Less32
+p.go:5:10: Proved Less32
+p.go:6:3: Disproved Eq64
+p.go:7:19: Induction variable: limits [0,?), increment 1
+p.go:7:19: removed nil check
+p.go:8:2: can inline F
+q.go:1:13: generated nil check
+unique/handle.go:26:6: removed nil check
+#src p.go:5	F		if x > 3 {
+#src p.go:6	F		x--
+#src p.go:7	F		for _, y := range *s {
+#src q.go:1	G	func G() { _ = *p }
+`
+	diags, err := gcdiag.Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The functions come from the #src lines; unique/handle.go, inlined
+	// from the standard library, is in none of them.
+	var funcs gcdiag.Funcs
+	if err := funcs.Read(strings.NewReader(output)); err != nil {
+		t.Fatal(err)
+	}
+	want := notes{
+		"p.go": {
+			5: {{10, "Proved Less32"}},
+			6: {{3, "Disproved Eq64"}},
+			7: {{19, "Induction variable: limits [0,?), increment 1"}, {19, "removed nil check"}},
+		},
+		"q.go": {
+			1: {{13, "generated nil check"}},
+		},
+	}
+	if got := collect(diags, &funcs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	src := "package p\n\nfunc f() {\n\tx := 1 // one\n}"
+	lines := map[int][]note{
+		4: {{2, "removed nil check"}, {7, "Proved Less64"}},
+		5: {{1, "Proved Eq64"}},
+	}
+	want := "package p\n\nfunc f() {\n\tx := 1 // one // col 2: removed nil check; col 7: Proved Less64\n} // col 1: Proved Eq64"
+	if got := string(annotate([]byte(src), lines)); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestAnnotateOpen checks that lines ending inside a raw string or a block
+// comment are not annotated, so the copy still compiles.
+func TestAnnotateOpen(t *testing.T) {
+	src := "package p\n\nvar s = `a\nb`\n\n/* c\nd */ var x = 1\n"
+	lines := map[int][]note{
+		3: {{9, "Proved Less64"}},
+		4: {{1, "Proved Less64"}},
+		6: {{1, "Proved Less64"}},
+		7: {{10, "Proved Less64"}},
+	}
+	want := "package p\n\nvar s = `a\nb` // col 1: Proved Less64\n\n/* c\nd */ var x = 1 // col 10: Proved Less64\n"
+	got := string(annotate([]byte(src), lines))
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "p.go", got, 0); err != nil {
+		t.Fatalf("annotated copy does not parse: %v", err)
+	}
+}
+
+// TestWriteFiles checks that files with the same name in different
+// directories are written to different copies.
+func TestWriteFiles(t *testing.T) {
+	src := t.TempDir()
+	var files []string
+	for _, dir := range []string{"a", "b/c"} {
+		name := filepath.Join(src, dir, "x.go")
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte("package x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, name)
+	}
+	ns := notes{
+		files[0]: {1: {{1, "Proved Less64"}}},
+		files[1]: {1: {{1, "Proved Eq64"}}},
+	}
+	out := t.TempDir()
+	written, err := writeFiles(out, ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(out, "a", "x.go"), filepath.Join(out, "b", "c", "x.go")}
+	if !reflect.DeepEqual(written, want) {
+		t.Fatalf("wrote %v, want %v", written, want)
+	}
+	for i, name := range written {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "package x // " + join(ns[files[i]][1]) + "\n"; string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func compile(t *testing.T, pkg string, tests bool) notes {
+	t.Helper()
+	out, err := gcdiag.Compile(pkg, tests, gcflags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags, err := gcdiag.Parse(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return collect(diags, new(gcdiag.Funcs))
+}
+
+// TestProve checks examples/prove, in which x > 3 is proved by x > 5.
+func TestProve(t *testing.T) {
+	ns := compile(t, "../prove", false)
+	lines := ns["../prove/foo.go"]
+	if !reflect.DeepEqual(lines[5], []note{{10, "Proved Less32"}}) {
+		t.Fatalf("line 5: got %v, want the comparison x > 3 proved", lines[5])
+	}
+
+	dir := t.TempDir()
+	written, err := writeFiles(dir, ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, "foo.go")}; !reflect.DeepEqual(written, want) {
+		t.Fatalf("wrote %v, want %v", written, want)
+	}
+	src, err := os.ReadFile(written[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), written[0], src, 0); err != nil {
+		t.Fatalf("annotated copy does not parse: %v", err)
+	}
+	if got := strings.Split(string(src), "\n")[4]; got != "\t\tif x > 3 { // <2> // col 10: Proved Less32" {
+		t.Fatalf("line 5 of the copy: got %q", got)
+	}
+}
+
+// TestNilcheck checks examples/nilcheck, in which only the first of a run
+// of accesses through t is checked for nil.
+func TestNilcheck(t *testing.T) {
+	lines := compile(t, "../nilcheck", true)["../nilcheck/nilcheck_test.go"]
+	for _, first := range []int{16, 29} {
+		if !hasNote(lines[first], "generated nil check") {
+			t.Errorf("line %d: got %v, want a nil check", first, lines[first])
+		}
+		for n := first + 1; n < first+7; n++ {
+			if !hasNote(lines[n], "removed nil check") {
+				t.Errorf("line %d: got %v, want the nil check removed", n, lines[n])
+			}
+		}
+	}
+}
+
+// TestInlinedStd checks examples/logline, into which standard library code
+// is inlined. The notes about it name files such as unique/handle.go,
+// which are not in the package, and must be left out rather than make -o
+// fail to read them.
+func TestInlinedStd(t *testing.T) {
+	ns := compile(t, "../logline", false)
+	if len(ns) == 0 {
+		t.Fatal("no notes")
+	}
+	for _, file := range ns.files() {
+		if !strings.HasPrefix(file, "../logline/") {
+			t.Errorf("notes for %s, which is not in the package", file)
+		}
+	}
+	if _, err := writeFiles(t.TempDir(), ns); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hasNote(ns []note, msg string) bool {
+	for _, n := range ns {
+		if n.msg == msg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+// provereport shows what the compiler's prove and nilcheck passes removed.
+//
+// It compiles a package with -gcflags=-d=ssa/prove/debug=1,nil and
+// prints each source line about which either pass said something, with
+// the messages appended as a comment: "Proved" for a comparison or bounds
+// check the prove pass showed to be always true, and so removed, and
+// "removed nil check" or "generated nil check" from the nilcheck pass.
+//
+//	go run ./examples/provereport ./examples/prove
+//	go run ./examples/provereport -test ./examples/nilcheck
+//
+// With -o it writes an annotated copy of each source file to a directory
+// instead, keeping the files' relative paths. The messages are appended to
+// the lines they refer to, so the copies have the same line numbers as the
+// originals.
+//
+//	go run ./examples/provereport -o /tmp/prove ./examples/prove
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/grafana/high-performance-go-workshop/examples/gcdiag"
+)
+
+const gcflags = "-d=ssa/prove/debug=1,nil"
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("provereport: ")
+	dir := flag.String("o", "", "write annotated copies of the source files to `dir`")
+	tests := flag.Bool("test", false, "compile the package's test files too")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: provereport [-o dir] [-test] [package]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	pkg := "."
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		pkg = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	out, err := gcdiag.Compile(pkg, *tests, gcflags)
+	if err != nil {
+		log.Fatal(err)
+	}
+	diags, err := gcdiag.Parse(bytes.NewReader(out))
+	if err != nil {
+		log.Fatal(err)
+	}
+	funcs := new(gcdiag.Funcs)
+	ns := collect(diags, funcs)
+	if *dir == "" {
+		if err := writeLines(os.Stdout, ns, funcs); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	written, err := writeFiles(*dir, ns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range written {
+		fmt.Println(name)
+	}
+}
@@ -0,0 +1,238 @@
+// range compares ways of walking memory. range_test.go shows the cost of
+// copying each element of an array of 4KB structs. This file measures
+// latency and bandwidth for different access patterns as the working set
+// grows from 4KB to 1GB: the time per access steps up as the working set
+// outgrows each level of cache, then the TLB.
+//
+//	go run ./examples/range -max 256MB > staircase.csv
+//
+// The same measurements are available as benchmarks, which report the
+// time per access as ns/op. They stop at 4MB unless given a larger -max:
+//
+//	go test -bench Access ./examples/range -max=1GB
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/grafana/high-performance-go-workshop/examples/bytesize"
+)
+
+const (
+	wordSize = 8
+	lineSize = 64   // bytes in a cache line
+	pageSize = 4096 // bytes in a page
+)
+
+// pattern is a way of accessing a buffer.
+type pattern struct {
+	name string
+	// bytes is the number of bytes moved from memory per access, once
+	// the buffer is larger than the caches.
+	bytes int64
+	// setup prepares a buffer, or returns nil if buf may be used as is.
+	setup func(buf []uint64) []uint64
+	// access makes n accesses to buf, whose length is a power of two,
+	// and returns a value computed from the words read.
+	access func(buf []uint64, n int) uint64
+}
+
+var patterns = []pattern{
+	{"sequential", wordSize, nil, sequential},
+	{"stride64", lineSize, nil, strided(lineSize)},
+	{"stride4096", lineSize, nil, strided(pageSize)},
+	{"random", lineSize, nil, random},
+	{"chase", lineSize, newChain, chase},
+}
+
+// sequential reads every word in order, wrapping around at the end.
+func sequential(buf []uint64, n int) uint64 {
+	var sum uint64
+	mask := len(buf) - 1
+	for i := range n {
+		sum += buf[i&mask]
+	}
+	return sum
+}
+
+// strided returns a function which reads the first word of every
+// stride bytes. With a stride of a cache line each access is to a new
+// line; with a stride of a page, to a new page, which needs a new TLB
+// entry. This is the pattern of BenchmarkRange.
+func strided(stride int) func([]uint64, int) uint64 {
+	step := stride / wordSize
+	return func(buf []uint64, n int) uint64 {
+		var sum uint64
+		mask := len(buf) - 1
+		for i := range n {
+			sum += buf[(i*step)&mask]
+		}
+		return sum
+	}
+}
+
+// random reads words chosen by a xorshift generator. The address of each
+// read does not depend on the one before, so the cpu can have several
+// misses outstanding at once.
+func random(buf []uint64, n int) uint64 {
+	var sum uint64
+	mask := uint64(len(buf) - 1)
+	x := uint64(88172645463325252)
+	for range n {
+		x ^= x << 13
+		x ^= x >> 7
+		x ^= x << 17
+		sum += buf[x&mask]
+	}
+	return sum
+}
+
+// chase follows the chain built by newChain. Each read gives the index of
+// the next, so every miss is paid for in full: this is the latency of
+// memory, not its bandwidth.
+func chase(buf []uint64, n int) uint64 {
+	var i uint64
+	for range n {
+		i = buf[i]
+	}
+	return i
+}
+
+// newChain links the first word of each cache line in buf into a single
+// cycle, in random order, with Sattolo's algorithm. Each link holds the
+// index of the next.
+func newChain(buf []uint64) []uint64 {
+	const step = lineSize / wordSize
+	nodes := len(buf) / step
+	if nodes < 2 {
+		buf[0] = 0
+		return buf
+	}
+	r := rand.New(rand.NewSource(1))
+	// Start with each node pointing at itself, then swap successors.
+	for i := range nodes {
+		buf[i*step] = uint64(i * step)
+	}
+	for i := nodes - 1; i > 0; i-- {
+		j := r.Intn(i)
+		buf[i*step], buf[j*step] = buf[j*step], buf[i*step]
+	}
+	return buf
+}
+
+// sizes returns the powers of two from smallest to largest bytes.
+func sizes(smallest, largest int64) []int64 {
+	var s []int64
+	for n := int64(pageSize); n <= largest; n *= 2 {
+		if n >= smallest {
+			s = append(s, n)
+		}
+	}
+	return s
+}
+
+// buffers reuses the memory for the largest buffer asked for so far, so
+// that a sweep of sizes, or a benchmark run many times while its b.N is
+// calibrated, does not allocate a gigabyte each time.
+type buffers struct {
+	mem []uint64
+}
+
+// get returns a buffer of size bytes prepared for p.
+func (bs *buffers) get(size int64, p pattern) []uint64 {
+	n := int(size / wordSize)
+	if len(bs.mem) < n {
+		bs.mem = nil // let the old buffer go first
+		bs.mem = make([]uint64, n)
+		for i := range bs.mem {
+			bs.mem[i] = uint64(i)
+		}
+	}
+	buf := bs.mem[:n]
+	if p.setup != nil {
+		buf = p.setup(buf)
+	}
+	return buf
+}
+
+// sink keeps the result of each access function live.
+var sink uint64
+
+// measurement is the result of benchmarking one pattern at one size.
+type measurement struct {
+	pattern string
+	size    int64
+	ns      float64 // per access
+	mbs     float64 // MB/s
+}
+
+// measure times p's accesses to a buffer of size bytes. Like a benchmark,
+// it increases the number of accesses until they take at least d.
+func measure(bs *buffers, p pattern, size int64, d time.Duration) measurement {
+	buf := bs.get(size, p)
+	n := 1 << 10
+	for {
+		start := time.Now()
+		sink = p.access(buf, n)
+		elapsed := time.Since(start)
+		if elapsed >= d {
+			ns := float64(elapsed.Nanoseconds()) / float64(n)
+			return measurement{
+				pattern: p.name,
+				size:    size,
+				ns:      ns,
+				mbs:     float64(p.bytes) * 1e3 / ns,
+			}
+		}
+		// Aim a fifth past d, but grow by at most 100x at a time.
+		next := float64(n) * 1.2 * float64(d) / float64(max(elapsed, 1))
+		n = int(min(max(next, float64(2*n)), float64(100*n)))
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("range: ")
+	minFlag := flag.String("min", "4KB", "smallest working set")
+	maxFlag := flag.String("max", "1GB", "largest working set")
+	d := flag.Duration("time", 200*time.Millisecond, "time to spend on each measurement")
+	only := flag.String("pattern", "", "measure only the named `pattern`: sequential, stride64, stride4096, random or chase")
+	flag.Parse()
+	smallest, err := bytesize.Parse(*minFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	largest, err := bytesize.Parse(*maxFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"pattern", "bytes", "size", "ns_per_access", "mb_per_s"})
+	var bs buffers
+	for _, size := range sizes(smallest, largest) {
+		for _, p := range patterns {
+			if *only != "" && p.name != *only {
+				continue
+			}
+			m := measure(&bs, p, size, *d)
+			w.Write([]string{
+				m.pattern,
+				strconv.FormatInt(m.size, 10),
+				bytesize.Format(m.size),
+				strconv.FormatFloat(m.ns, 'f', 3, 64),
+				strconv.FormatFloat(m.mbs, 'f', 1, 64),
+			})
+			w.Flush()
+		}
+	}
+	if err := w.Error(); err != nil {
+		log.Fatal(err)
+	}
+}
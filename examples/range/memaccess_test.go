@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/grafana/high-performance-go-workshop/examples/bytesize"
+)
+
+func TestNewChain(t *testing.T) {
+	for _, size := range []int64{lineSize, 2 * lineSize, pageSize, 1 << 20} {
+		buf := newChain(make([]uint64, size/wordSize))
+		nodes := int(size / lineSize)
+		seen := make(map[uint64]bool)
+		var i uint64
+		for range nodes {
+			if i%(lineSize/wordSize) != 0 {
+				t.Fatalf("%d bytes: link to %d, which does not start a line", size, i)
+			}
+			if seen[i] {
+				t.Fatalf("%d bytes: %d visited twice in %d steps", size, i, nodes)
+			}
+			seen[i] = true
+			i = buf[i]
+		}
+		if i != 0 {
+			t.Fatalf("%d bytes: chain does not return to the start after %d steps", size, nodes)
+		}
+	}
+}
+
+func TestAccess(t *testing.T) {
+	buf := make([]uint64, 1024)
+	for i := range buf {
+		buf[i] = uint64(i)
+	}
+	if got, want := sequential(buf, 2*len(buf)), uint64(2*1023*1024/2); got != want {
+		t.Errorf("sequential: got %d, want %d", got, want)
+	}
+	// Eight words to a line: 0, 8, 16, ... 1016.
+	if got, want := strided(lineSize)(buf, 128), uint64(8*127*128/2); got != want {
+		t.Errorf("stride64: got %d, want %d", got, want)
+	}
+	// 512 words to a page, so in a buffer of two pages, 0, 512, 0, 512.
+	if got, want := strided(pageSize)(buf, 4), uint64(1024); got != want {
+		t.Errorf("stride4096: got %d, want %d", got, want)
+	}
+	if got := chase(newChain(buf), 128); got != 0 {
+		t.Errorf("chase: after a full cycle got %d, want 0", got)
+	}
+}
+
+func TestSizes(t *testing.T) {
+	got := sizes(16<<10, 1<<30)
+	if len(got) != 17 || got[0] != 16<<10 || got[16] != 1<<30 {
+		t.Fatalf("sizes(16KB, 1GB) = %v", got)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	var bs buffers
+	for _, p := range patterns {
+		m := measure(&bs, p, 64<<10, time.Millisecond)
+		if m.pattern != p.name || m.size != 64<<10 || m.ns <= 0 || m.mbs <= 0 {
+			t.Errorf("%s: %+v", p.name, m)
+		}
+	}
+}
+
+var benchBuffers buffers
+
+var maxFlag = flag.String("max", "4MB", "largest working set for BenchmarkAccess")
+
+// BenchmarkAccess reports, as ns/op, the time for each access, and as
+// MB/s, the rate at which data is moved from memory.
+func BenchmarkAccess(b *testing.B) {
+	largest, err := bytesize.Parse(*maxFlag)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, p := range patterns {
+		b.Run(p.name, func(b *testing.B) {
+			for size := int64(pageSize); size <= largest; size *= 4 {
+				buf := benchBuffers.get(size, p)
+				b.Run(bytesize.Format(size), func(b *testing.B) {
+					b.SetBytes(p.bytes)
+					Result = int(p.access(buf, b.N))
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package p
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Semaphore is a weighted semaphore. Unlike the channel semaphore in
+// semaphore.go, callers may acquire more than one slot at a time, give up
+// waiting when their context is cancelled, and are served in FIFO order so
+// a large request is not starved by a stream of small ones.
+type Semaphore struct {
+	mu      sync.Mutex
+	size    int64
+	cur     int64
+	waiters list.List // of waiter
+}
+
+type waiter struct {
+	n     int64
+	ready chan struct{} // closed when the slots have been acquired
+}
+
+// NewSemaphore returns a Semaphore with n slots.
+func NewSemaphore(n int64) *Semaphore {
+	return &Semaphore{size: n}
+}
+
+// Acquire acquires n slots, blocking until they are available or ctx is
+// done. On failure it returns ctx.Err() and leaves the semaphore unchanged.
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	done := ctx.Done()
+	s.mu.Lock()
+	select {
+	case <-done:
+		// prefer failing over acquiring when ctx is already done.
+		s.mu.Unlock()
+		return ctx.Err()
+	default:
+	}
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	if n > s.size {
+		// can never succeed, wait for ctx to be done.
+		s.mu.Unlock()
+		<-done
+		return ctx.Err()
+	}
+
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(waiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-done:
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// acquired after ctx was done, give the slots back.
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			front := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// if we were blocking the queue, the waiters behind us may
+			// now be able to proceed.
+			if front && s.size > s.cur {
+				s.notifyWaiters()
+			}
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// TryAcquire acquires n slots without blocking, reporting whether it
+// succeeded.
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	ok := s.size-s.cur >= n && s.waiters.Len() == 0
+	if ok {
+		s.cur += n
+	}
+	s.mu.Unlock()
+	return ok
+}
+
+// Release releases n slots.
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	s.cur -= n
+	if s.cur < 0 {
+		s.mu.Unlock()
+		panic("semaphore: released more than held")
+	}
+	s.notifyWaiters()
+	s.mu.Unlock()
+}
+
+// notifyWaiters wakes waiters in FIFO order for as long as there are enough
+// free slots for the waiter at the front of the queue. s.mu must be held.
+func (s *Semaphore) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+		w := next.Value.(waiter)
+		if s.size-s.cur < w.n {
+			// stop, rather than looking for a smaller waiter further back,
+			// so that large requests are not starved.
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
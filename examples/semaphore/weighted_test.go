@@ -0,0 +1,232 @@
+package p
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSemaphoreTryAcquire(t *testing.T) {
+	s := NewSemaphore(3)
+	if !s.TryAcquire(2) {
+		t.Fatal("TryAcquire(2): want true")
+	}
+	if s.TryAcquire(2) {
+		t.Fatal("TryAcquire(2): want false with 1 slot free")
+	}
+	if !s.TryAcquire(1) {
+		t.Fatal("TryAcquire(1): want true")
+	}
+	s.Release(3)
+	if !s.TryAcquire(3) {
+		t.Fatal("TryAcquire(3): want true after Release")
+	}
+}
+
+func TestSemaphoreReleaseTooMany(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Release: want panic")
+		}
+	}()
+	NewSemaphore(1).Release(1)
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	s := NewSemaphore(10)
+	ctx := context.Background()
+	if err := s.Acquire(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// queue a large request followed by a small one.
+	order := make(chan int64, 2)
+	for i, n := range []int64{10, 1} {
+		n := n
+		go func() {
+			if err := s.Acquire(ctx, n); err != nil {
+				t.Error(err)
+			}
+			order <- n
+		}()
+		waitForWaiters(t, s, i+1)
+	}
+	if s.TryAcquire(1) {
+		t.Fatal("TryAcquire: must not jump the queue")
+	}
+
+	s.Release(5)
+	select {
+	case n := <-order:
+		t.Fatalf("Acquire(%d) succeeded ahead of the large waiter", n)
+	case <-time.After(10 * time.Millisecond):
+	}
+	s.Release(5)
+	if n := <-order; n != 10 {
+		t.Fatalf("want Acquire(10) first, got Acquire(%d)", n)
+	}
+	s.Release(10)
+	if n := <-order; n != 1 {
+		t.Fatalf("want Acquire(1) second, got Acquire(%d)", n)
+	}
+}
+
+// waitForWaiters waits until want goroutines are queued on s.
+func waitForWaiters(t *testing.T, s *Semaphore, want int) {
+	for i := 0; i < 1000; i++ {
+		s.mu.Lock()
+		got := s.waiters.Len()
+		s.mu.Unlock()
+		if got == want {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d waiters", want)
+}
+
+func TestSemaphoreCancel(t *testing.T) {
+	s := NewSemaphore(2)
+	if err := s.Acquire(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// a waiter at the front of the queue which is cancelled must not block
+	// smaller waiters behind it.
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() { errc <- s.Acquire(ctx, 2) }()
+	waitForWaiters(t, s, 1)
+	acquired := make(chan struct{})
+	go func() {
+		if err := s.Acquire(context.Background(), 1); err != nil {
+			t.Error(err)
+		}
+		close(acquired)
+	}()
+	waitForWaiters(t, s, 2)
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("Acquire: want %v, got %v", context.Canceled, err)
+	}
+	<-acquired
+	s.Release(2)
+	if !s.TryAcquire(2) {
+		t.Fatal("cancelled Acquire leaked a slot")
+	}
+}
+
+func TestSemaphoreAlreadyCancelled(t *testing.T) {
+	s := NewSemaphore(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Acquire(ctx, 1); err != context.Canceled {
+		t.Fatalf("Acquire: want %v, got %v", context.Canceled, err)
+	}
+	if !s.TryAcquire(1) {
+		t.Fatal("cancelled Acquire leaked a slot")
+	}
+}
+
+func TestSemaphoreTooLarge(t *testing.T) {
+	s := NewSemaphore(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Acquire(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatalf("Acquire: want %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+// run with -race; goroutines acquire and cancel at random and the
+// semaphore must end up with every slot free.
+func TestSemaphoreStress(t *testing.T) {
+	const size = 5
+	s := NewSemaphore(size)
+	var held int64
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for g := 0; g < 20; g++ {
+		g := g
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				n := int64(1 + (g+i)%3)
+				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i%3)*time.Microsecond)
+				if err := s.Acquire(ctx, n); err == nil {
+					mu.Lock()
+					held += n
+					if held > size {
+						t.Errorf("%d slots held, semaphore size is %d", held, size)
+					}
+					mu.Unlock()
+					// hold the slots for a while, so that other
+					// goroutines' holds overlap this one.
+					runtime.Gosched()
+					mu.Lock()
+					held -= n
+					mu.Unlock()
+					s.Release(n)
+				}
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	if !s.TryAcquire(size) {
+		t.Fatal("slots leaked")
+	}
+}
+
+func BenchmarkChannelSemaphore(b *testing.B) {
+	sem := make(chan struct{}, 10)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			sem <- struct{}{}
+			<-sem
+		}
+	})
+}
+
+func BenchmarkSemaphore(b *testing.B) {
+	s := NewSemaphore(10)
+	ctx := context.Background()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := s.Acquire(ctx, 1); err != nil {
+				b.Error(err)
+				return
+			}
+			s.Release(1)
+		}
+	})
+}
+
+// with only one slot, every Acquire contends and most have to queue.
+func BenchmarkChannelSemaphoreContended(b *testing.B) {
+	sem := make(chan struct{}, 1)
+	b.SetParallelism(8)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			sem <- struct{}{}
+			<-sem
+		}
+	})
+}
+
+func BenchmarkSemaphoreContended(b *testing.B) {
+	s := NewSemaphore(1)
+	ctx := context.Background()
+	b.SetParallelism(8)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := s.Acquire(ctx, 1); err != nil {
+				b.Error(err)
+				return
+			}
+			s.Release(1)
+		}
+	})
+}
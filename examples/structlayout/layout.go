@@ -0,0 +1,164 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+)
+
+// layout describes the memory layout of a named struct type.
+type layout struct {
+	pos  token.Position
+	name string
+	size int64
+	// align is the alignment of the struct as a whole, that of its most
+	// strictly aligned field.
+	align int64
+	// padding is the number of bytes the compiler inserts between
+	// fields, and after the last, to align them.
+	padding int64
+	// explicit is the size of the blank fields, "_ [7]byte" and the like,
+	// added by hand. They are usually padding added deliberately, for
+	// example to keep two fields on separate cache lines, so a struct
+	// which has them is never reordered.
+	explicit int64
+
+	// optimal is the size of the struct with its fields in the order
+	// given by order, which is by index into decl.Fields.List.
+	optimal int64
+	order   []int
+
+	file *ast.File
+	decl *ast.StructType
+}
+
+// wasted returns the number of bytes saved by reordering the fields.
+func (l *layout) wasted() int64 { return l.size - l.optimal }
+
+// reorder reports whether the fields should be reordered.
+func (l *layout) reorder() bool { return l.wasted() > 0 && l.explicit == 0 }
+
+// fieldNames returns the names of the fields in the suggested order.
+// Embedded fields are named by their type.
+func (l *layout) fieldNames() []string {
+	var names []string
+	for _, i := range l.order {
+		f := l.decl.Fields.List[i]
+		if len(f.Names) == 0 {
+			names = append(names, types.ExprString(f.Type))
+		}
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+	}
+	return names
+}
+
+// layouts returns the layout of each named struct type declared in
+// files. Generic types are skipped as their size depends on their type
+// arguments.
+func layouts(fset *token.FileSet, files []*ast.File, info *types.Info, sizes types.Sizes) []*layout {
+	var ls []*layout
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok || spec.TypeParams != nil {
+				return true
+			}
+			decl, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			obj, ok := info.Defs[spec.Name].(*types.TypeName)
+			if !ok {
+				return true
+			}
+			st, ok := obj.Type().Underlying().(*types.Struct)
+			if !ok {
+				return true
+			}
+			l := &layout{
+				pos:  fset.Position(spec.Name.Pos()),
+				name: spec.Name.Name,
+				file: file,
+				decl: decl,
+			}
+			l.measure(st, sizes)
+			ls = append(ls, l)
+			return true
+		})
+	}
+	return ls
+}
+
+// measure fills in the sizes and the optimal order of the fields of st.
+func (l *layout) measure(st *types.Struct, sizes types.Sizes) {
+	l.size = sizes.Sizeof(st)
+	l.align = sizes.Alignof(st)
+	var sum int64
+	for i := range st.NumFields() {
+		f := st.Field(i)
+		n := sizes.Sizeof(f.Type())
+		sum += n
+		if f.Name() == "_" {
+			l.explicit += n
+		}
+	}
+	l.padding = l.size - sum
+
+	// A declaration such as "a, b int32" declares several fields with
+	// the same type. It is kept together, which costs nothing as its
+	// fields have the same alignment.
+	type group struct {
+		index       int // in decl.Fields.List
+		vars        []*types.Var
+		tags        []string
+		size, align int64
+	}
+	var groups []group
+	next := 0
+	for i, f := range l.decl.Fields.List {
+		g := group{index: i}
+		n := max(len(f.Names), 1)
+		for range n {
+			v := st.Field(next)
+			g.vars = append(g.vars, v)
+			g.tags = append(g.tags, st.Tag(next))
+			g.size += sizes.Sizeof(v.Type())
+			next++
+		}
+		g.align = sizes.Alignof(g.vars[0].Type())
+		groups = append(groups, g)
+	}
+
+	// Ordering the fields by decreasing alignment leaves no gaps between
+	// them, since each field's size is a multiple of its alignment. Zero
+	// sized fields go first: at the end of a struct the compiler pads
+	// them so that their address is not past the end of the struct.
+	slices.SortStableFunc(groups, func(a, b group) int {
+		if za, zb := a.size == 0, b.size == 0; za != zb {
+			if za {
+				return -1
+			}
+			return 1
+		}
+		return int(b.align - a.align)
+	})
+	var vars []*types.Var
+	var tags []string
+	for _, g := range groups {
+		l.order = append(l.order, g.index)
+		vars = append(vars, g.vars...)
+		tags = append(tags, g.tags...)
+	}
+	l.optimal = sizes.Sizeof(types.NewStruct(vars, tags))
+	if l.optimal >= l.size {
+		// keep the declared order
+		l.optimal = l.size
+		l.order = l.order[:0]
+		for i := range l.decl.Fields.List {
+			l.order = append(l.order, i)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func loadLayouts(t *testing.T, pattern string) (*packages.Package, map[string]*layout) {
+	t.Helper()
+	pkgs, err := load(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := pkgs[0]
+	m := make(map[string]*layout)
+	for _, l := range layouts(pkg.Fset, pkg.Syntax, pkg.TypesInfo, pkg.TypesSizes) {
+		m[l.name] = l
+	}
+	return pkg, m
+}
+
+// TestFields checks examples/fields. S wastes 11 of its 24 bytes, and
+// takes 16 with its float64 first. SPadded is S with its padding written
+// out, which is not reordered.
+func TestFields(t *testing.T) {
+	_, ls := loadLayouts(t, "../fields")
+	s, padded := ls["S"], ls["SPadded"]
+	if s == nil || padded == nil {
+		t.Fatalf("got %v, want S and SPadded", ls)
+	}
+	if s.size != 24 || s.align != 8 || s.padding != 11 || s.explicit != 0 || s.optimal != 16 || !s.reorder() {
+		t.Errorf("S: got size %d align %d padding %d explicit %d optimal %d", s.size, s.align, s.padding, s.explicit, s.optimal)
+	}
+	if got, want := s.fieldNames(), []string{"b", "c", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("S: got order %v, want %v", got, want)
+	}
+	if padded.size != 24 || padded.padding != 0 || padded.explicit != 11 || padded.reorder() {
+		t.Errorf("SPadded: got size %d padding %d explicit %d, reorder %v", padded.size, padded.padding, padded.explicit, padded.reorder())
+	}
+}
+
+func TestPadded(t *testing.T) {
+	_, ls := loadLayouts(t, "./testdata/padded")
+	for _, tt := range []struct {
+		name                             string
+		size, padding, explicit, optimal int64
+		order                            []string
+	}{
+		{"counter", 64, 0, 56, 64, []string{"n", "_"}},
+		{"pair", 128, 0, 112, 128, []string{"a", "_", "b", "_"}},
+		{"stats", 40, 10, 0, 32, []string{"hits", "misses", "mu", "count", "ready", "closed"}},
+		{"tail", 16, 8, 0, 8, []string{"done", "n"}},
+		{"packed", 24, 0, 0, 24, []string{"a", "b", "c", "d", "e"}},
+	} {
+		l := ls[tt.name]
+		if l == nil {
+			t.Errorf("%s: not reported", tt.name)
+			continue
+		}
+		if l.size != tt.size || l.padding != tt.padding || l.explicit != tt.explicit || l.optimal != tt.optimal {
+			t.Errorf("%s: got size %d padding %d explicit %d optimal %d, want %d %d %d %d",
+				tt.name, l.size, l.padding, l.explicit, l.optimal, tt.size, tt.padding, tt.explicit, tt.optimal)
+		}
+		if got := l.fieldNames(); !reflect.DeepEqual(got, tt.order) {
+			t.Errorf("%s: got order %v, want %v", tt.name, got, tt.order)
+		}
+	}
+	if _, ok := ls["generic"]; ok {
+		t.Errorf("generic type reported")
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	pkg, ls := loadLayouts(t, "./testdata/padded")
+	file := ls["stats"].file
+	src, err := os.ReadFile(pkg.Fset.File(file.Pos()).Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all []*layout
+	for _, l := range ls {
+		all = append(all, l)
+	}
+	out, skipped, err := rewrite(pkg.Fset, file, src, all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("skipped %v", skipped)
+	}
+	for _, want := range []string{`
+type stats struct {
+	hits, misses uint64 // guarded by mu
+	mu           sync.Mutex
+	count        int32
+	// ready is set once the stats are first collected.
+	ready  bool
+	closed bool
+}
+`, `
+type tail struct {
+	done struct{}
+	n    int64
+}
+`, `
+type pair struct {
+	a uint64
+	_ [cacheLineSize - 8]byte
+	b uint64
+	_ [cacheLineSize - 8]byte
+}
+`} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("rewritten source does not contain%s\ngot:\n%s", want, out)
+		}
+	}
+}
+
+func TestRewriteStrayComment(t *testing.T) {
+	pkg, ls := loadLayouts(t, "./testdata/stray")
+	l := ls["S"]
+	src, err := os.ReadFile(pkg.Fset.File(l.file.Pos()).Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, skipped, err := rewrite(pkg.Fset, l.file, src, []*layout{l})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || !bytes.Equal(out, src) {
+		t.Fatalf("struct with a stray comment rewritten:\n%s", out)
+	}
+}
@@ -0,0 +1,143 @@
+// structlayout reports the memory layout of struct types.
+//
+// For each named struct type in the packages given it prints the size,
+// alignment and padding of the struct, as laid out by the gc compiler for
+// the target GOARCH, and the size it would have with its fields in the
+// optimal order, most strictly aligned first.
+//
+//	go run ./examples/structlayout ./examples/fields
+//
+// Where reordering would make a struct smaller, the new order is listed,
+// and with -w the declarations are rewritten in that order. Structs with
+// blank fields, such as "_ [56]byte", are taken to be padded by hand, for
+// example to keep a field on a cache line of its own, and are not
+// reordered.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("structlayout: ")
+	write := flag.Bool("w", false, "rewrite struct declarations in the suggested order")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: structlayout [-w] [packages]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	pkgs, err := load(patterns...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		ls := layouts(pkg.Fset, pkg.Syntax, pkg.TypesInfo, pkg.TypesSizes)
+		if err := writeReport(os.Stdout, ls); err != nil {
+			log.Fatal(err)
+		}
+		if *write {
+			if err := rewriteFiles(pkg, ls); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+}
+
+// load loads and type checks the packages matching patterns.
+func load(patterns ...string) ([]*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax |
+			packages.NeedTypes | packages.NeedTypesInfo | packages.NeedTypesSizes,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading %s", strings.Join(patterns, " "))
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages match %s", strings.Join(patterns, " "))
+	}
+	return pkgs, nil
+}
+
+// writeReport writes a line for each struct, then the suggested order of
+// the fields of each struct which reordering would make smaller.
+func writeReport(w io.Writer, ls []*layout) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "POSITION\tTYPE\tSIZE\tALIGN\tPADDING\tEXPLICIT\tOPTIMAL\n")
+	for _, l := range ls {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\n", rel(l.pos), l.name, l.size, l.align, l.padding, l.explicit, l.optimal)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, l := range ls {
+		if !l.reorder() {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "%s: %s: reorder as %s to save %d bytes\n", rel(l.pos), l.name, strings.Join(l.fieldNames(), ", "), l.wasted())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteFiles rewrites the files of pkg which declare structs to be
+// reordered.
+func rewriteFiles(pkg *packages.Package, ls []*layout) error {
+	byFile := make(map[*ast.File][]*layout)
+	for _, l := range ls {
+		if l.reorder() {
+			byFile[l.file] = append(byFile[l.file], l)
+		}
+	}
+	for file, ls := range byFile {
+		name := pkg.Fset.File(file.Pos()).Name()
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		out, skipped, err := rewrite(pkg.Fset, file, src, ls)
+		if err != nil {
+			return err
+		}
+		for _, l := range skipped {
+			log.Printf("%s: %s: not rewritten, it contains comments which belong to no field", rel(l.pos), l.name)
+		}
+		if err := os.WriteFile(name, out, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rel returns pos with its file name relative to the current directory,
+// if it is below it.
+func rel(pos token.Position) string {
+	if wd, err := os.Getwd(); err == nil {
+		if name, err := filepath.Rel(wd, pos.Filename); err == nil && !strings.HasPrefix(name, "..") {
+			pos.Filename = name
+		}
+	}
+	return pos.String()
+}
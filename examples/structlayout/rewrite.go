@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"slices"
+)
+
+// rewrite returns src, the source of file, with the fields of each struct
+// in ls reordered as suggested, and formatted. A field keeps its doc and
+// line comments. Structs containing comments which belong to no field,
+// and so have no place in the new order, are left alone and returned in
+// skipped.
+func rewrite(fset *token.FileSet, file *ast.File, src []byte, ls []*layout) (out []byte, skipped []*layout, err error) {
+	base := fset.File(file.Pos()).Base()
+	offset := func(p token.Pos) int { return int(p) - base }
+
+	// Work from the end of the file, so earlier offsets stay valid.
+	ls = slices.Clone(ls)
+	slices.SortFunc(ls, func(a, b *layout) int { return int(b.decl.Pos() - a.decl.Pos()) })
+	out = slices.Clone(src)
+	for _, l := range ls {
+		if !l.reorder() {
+			continue
+		}
+		fields := l.decl.Fields
+		if stray(file, fields) {
+			skipped = append(skipped, l)
+			continue
+		}
+		var body bytes.Buffer
+		body.WriteString("{\n")
+		for _, i := range l.order {
+			f := fields.List[i]
+			start, end := f.Pos(), f.End()
+			if f.Doc != nil {
+				start = f.Doc.Pos()
+			}
+			if f.Comment != nil {
+				end = f.Comment.End()
+			}
+			body.Write(src[offset(start):offset(end)])
+			body.WriteByte('\n')
+		}
+		body.WriteString("}")
+		out = slices.Concat(out[:offset(fields.Opening)], body.Bytes(), out[offset(fields.Closing)+1:])
+	}
+	formatted, err := format.Source(out)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", fset.File(file.Pos()).Name(), err)
+	}
+	return formatted, skipped, nil
+}
+
+// stray reports whether there is a comment inside fields which is neither
+// the doc comment nor the line comment of a field.
+func stray(file *ast.File, fields *ast.FieldList) bool {
+	owned := make(map[*ast.CommentGroup]bool)
+	for _, f := range fields.List {
+		owned[f.Doc] = true
+		owned[f.Comment] = true
+	}
+	for _, cg := range file.Comments {
+		if cg.Pos() > fields.Opening && cg.End() < fields.Closing && !owned[cg] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package padded
+
+import "sync"
+
+const cacheLineSize = 64
+
+// counter is padded to fill a cache line, so that counters in an array
+// do not share one.
+type counter struct {
+	n uint64
+	_ [cacheLineSize - 8]byte
+}
+
+// pair keeps a and b on separate cache lines.
+type pair struct {
+	a uint64
+	_ [cacheLineSize - 8]byte
+	b uint64
+	_ [cacheLineSize - 8]byte
+}
+
+// stats is unpadded, and wastes space between its fields.
+type stats struct {
+	// ready is set once the stats are first collected.
+	ready        bool
+	mu           sync.Mutex
+	hits, misses uint64 // guarded by mu
+	closed       bool
+	count        int32
+}
+
+// tail ends in a zero sized field, which the compiler pads.
+type tail struct {
+	n    int64
+	done struct{}
+}
+
+// packed needs no padding.
+type packed struct {
+	a, b uint64
+	c    uint32
+	d, e uint16
+}
+
+// generic is skipped, its size depends on T.
+type generic[T any] struct {
+	ok bool
+	v  T
+}
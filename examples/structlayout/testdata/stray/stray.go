@@ -0,0 +1,10 @@
+package stray
+
+type S struct {
+	a bool
+
+	// This comment belongs to no field.
+
+	b float64
+	c bool
+}
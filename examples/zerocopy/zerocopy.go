@@ -0,0 +1,77 @@
+// Package zerocopy compares, hashes and looks up byte slices against
+// strings without copying them.
+//
+// As examples/byteseq shows, the compiler avoids copying a []byte
+// converted to a string when the conversion is used directly in a
+// comparison, a map index or a concatenation, but not when the string is
+// assigned to a variable or passed to a function. The helpers here keep
+// that property across function calls: String and Bytes reinterpret the
+// memory of their argument with unsafe.String and unsafe.Slice, and the
+// other helpers use them only for values which do not outlive the call.
+package zerocopy
+
+import (
+	"hash/maphash"
+	"slices"
+	"strings"
+	"unsafe"
+)
+
+// String returns a string which shares b's memory. The string changes if
+// b is modified, which breaks Go's guarantee that strings are immutable,
+// so b must not be modified while the string is in use. In particular the
+// string must not be used as a map key, or stored anywhere it may outlive
+// the current use of b.
+func String[B ~[]byte](b B) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// Bytes returns a slice which shares s's memory. The slice must not be
+// modified: the bytes of a string may be in read only memory.
+func Bytes[S ~string](s S) []byte {
+	return unsafe.Slice(unsafe.StringData(string(s)), len(s))
+}
+
+// Equal reports whether b and s hold the same bytes.
+func Equal[B ~[]byte, S ~string](b B, s S) bool {
+	return String(b) == string(s)
+}
+
+// Compare compares b and s lexically, returning -1, 0 or +1 like
+// strings.Compare.
+func Compare[B ~[]byte, S ~string](b B, s S) int {
+	return strings.Compare(String(b), string(s))
+}
+
+// HasPrefix reports whether b begins with prefix.
+func HasPrefix[B ~[]byte, S ~string](b B, prefix S) bool {
+	return strings.HasPrefix(String(b), string(prefix))
+}
+
+// Hash returns the same hash of b as maphash.String returns for a string
+// with the same contents, so that a table of strings can be probed with a
+// []byte.
+func Hash[B ~[]byte](seed maphash.Seed, b B) uint64 {
+	return maphash.String(seed, String(b))
+}
+
+// Lookup returns the value for key in m. The conversion in the index
+// expression is one the compiler does without copying.
+func Lookup[K ~string, V any, B ~[]byte](m map[K]V, key B) (V, bool) {
+	v, ok := m[K(key)]
+	return v, ok
+}
+
+// Store sets the value for key in m. The key is copied, as it must be:
+// a map key which shared key's memory would change when key did.
+func Store[K ~string, V any, B ~[]byte](m map[K]V, key B, v V) {
+	m[K(key)] = v
+}
+
+// Search returns the index of key in the sorted slice keys, and whether
+// it is there, like slices.BinarySearch.
+func Search[S ~string, B ~[]byte](keys []S, key B) (int, bool) {
+	return slices.BinarySearchFunc(keys, String(key), func(k S, t string) int {
+		return strings.Compare(string(k), t)
+	})
+}
@@ -0,0 +1,222 @@
+package zerocopy
+
+import (
+	"bytes"
+	"hash/maphash"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestStringSharesMemory(t *testing.T) {
+	b := []byte("hello")
+	s := String(b)
+	if s != "hello" {
+		t.Fatalf("String(%q) = %q", b, s)
+	}
+	// This is the hazard String's documentation warns of.
+	b[0] = 'j'
+	if s != "jello" {
+		t.Fatalf("after modifying b, s = %q, want it to change with b", s)
+	}
+	if String([]byte(nil)) != "" || String([]byte{}) != "" {
+		t.Fatal("String of an empty slice is not empty")
+	}
+}
+
+func TestBytes(t *testing.T) {
+	s := strings.Repeat("ab", 3)
+	if b := Bytes(s); string(b) != s || len(b) != cap(b) {
+		t.Fatalf("Bytes(%q) = %q, len %d cap %d", s, b, len(b), cap(b))
+	}
+	if b := Bytes(""); len(b) != 0 {
+		t.Fatalf("Bytes(\"\") = %q", b)
+	}
+}
+
+type name string
+type raw []byte
+
+func TestCompare(t *testing.T) {
+	for _, tt := range []struct {
+		b, s string
+	}{
+		{"", ""}, {"a", ""}, {"", "a"}, {"abc", "abd"}, {"abc", "ab"}, {"France", "France"},
+	} {
+		b := raw(tt.b)
+		if got, want := Equal(b, name(tt.s)), tt.b == tt.s; got != want {
+			t.Errorf("Equal(%q, %q) = %v", tt.b, tt.s, got)
+		}
+		if got, want := Compare(b, tt.s), strings.Compare(tt.b, tt.s); got != want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.b, tt.s, got, want)
+		}
+		if got, want := HasPrefix(b, tt.s), strings.HasPrefix(tt.b, tt.s); got != want {
+			t.Errorf("HasPrefix(%q, %q) = %v", tt.b, tt.s, got)
+		}
+	}
+}
+
+func FuzzCompare(f *testing.F) {
+	f.Add([]byte("France"), "France")
+	f.Add([]byte("Franc"), "France")
+	f.Fuzz(func(t *testing.T, b []byte, s string) {
+		if got, want := Compare(b, s), bytes.Compare(b, []byte(s)); got != want {
+			t.Fatalf("Compare(%q, %q) = %d, want %d", b, s, got, want)
+		}
+		if Equal(b, s) != bytes.Equal(b, []byte(s)) {
+			t.Fatalf("Equal(%q, %q) = %v", b, s, Equal(b, s))
+		}
+	})
+}
+
+func TestHash(t *testing.T) {
+	seed := maphash.MakeSeed()
+	for _, s := range []string{"", "France", strings.Repeat("x", 1000)} {
+		if got, want := Hash(seed, []byte(s)), maphash.String(seed, s); got != want {
+			t.Errorf("Hash(%q) = %#x, want %#x", s, got, want)
+		}
+	}
+}
+
+// TestStoreCopiesKey modifies a key after storing it. Had Store used
+// String, the map would now hold a key which no longer hashes to its
+// bucket.
+func TestStoreCopiesKey(t *testing.T) {
+	m := make(map[string]int)
+	key := []byte("France")
+	Store(m, key, 1)
+	copy(key, "Poland")
+	Store(m, key, 2)
+	if len(m) != 2 || m["France"] != 1 || m["Poland"] != 2 {
+		t.Fatalf("got %v, want France: 1, Poland: 2", m)
+	}
+	for k := range m {
+		if unsafeShares(k, key) {
+			t.Fatalf("map key %q shares memory with the key stored", k)
+		}
+	}
+	if v, ok := Lookup(m, key); v != 2 || !ok {
+		t.Fatalf("Lookup(%q) = %d, %v", key, v, ok)
+	}
+	copy(key, "Greece")
+	if v, ok := Lookup(m, key); v != 0 || ok {
+		t.Fatalf("Lookup(%q) = %d, %v", key, v, ok)
+	}
+}
+
+// unsafeShares reports whether s and b start at the same address.
+func unsafeShares(s string, b []byte) bool {
+	return len(s) > 0 && len(b) > 0 && &Bytes(s)[0] == &b[0]
+}
+
+func TestSearch(t *testing.T) {
+	keys := []name{"Algeria", "Brazil", "France", "Japan"}
+	key := []byte("France")
+	if i, ok := Search(keys, key); i != 2 || !ok {
+		t.Fatalf("Search(%q) = %d, %v", key, i, ok)
+	}
+	// Search keeps no reference to key.
+	copy(key, "Cuba\x00\x00")
+	key = key[:4]
+	if i, ok := Search(keys, key); i != 2 || ok {
+		t.Fatalf("Search(%q) = %d, %v", key, i, ok)
+	}
+	if keys[2] != "France" {
+		t.Fatalf("keys modified: %q", keys)
+	}
+}
+
+func TestNoAllocs(t *testing.T) {
+	m := map[string]int{"France": 1}
+	keys := []string{"Algeria", "France"}
+	key := []byte("France")
+	seed := maphash.MakeSeed()
+	allocs := testing.AllocsPerRun(100, func() {
+		Equal(key, "France")
+		Compare(key, "France")
+		Hash(seed, key)
+		Lookup(m, key)
+		Search(keys, key)
+	})
+	if allocs != 0 {
+		t.Fatalf("%v allocations, want 0", allocs)
+	}
+}
+
+var Result bool
+
+// BenchmarkEqual compares the cases in examples/byteseq, in which the
+// conversions are copied when assigned to variables, with Equal and
+// String, which never copy.
+func BenchmarkEqual(b *testing.B) {
+	x := bytes.Repeat([]byte{'a'}, 1<<20)
+	y := bytes.Repeat([]byte{'a'}, 1<<20)
+	s := string(y)
+	b.Run("inline", func(b *testing.B) {
+		b.ReportAllocs()
+		var r bool
+		for n := 0; n < b.N; n++ {
+			r = string(x) == string(y)
+		}
+		Result = r
+	})
+	b.Run("explicit", func(b *testing.B) {
+		b.ReportAllocs()
+		var r bool
+		for n := 0; n < b.N; n++ {
+			q := string(x)
+			r = q == s
+		}
+		Result = r
+	})
+	b.Run("Equal", func(b *testing.B) {
+		b.ReportAllocs()
+		var r bool
+		for n := 0; n < b.N; n++ {
+			r = Equal(x, s)
+		}
+		Result = r
+	})
+	b.Run("String", func(b *testing.B) {
+		b.ReportAllocs()
+		var r bool
+		for n := 0; n < b.N; n++ {
+			q := String(x)
+			r = q == s
+		}
+		Result = r
+	})
+}
+
+var capitals = []string{"Algeria", "Argentina", "Australia", "Austria", "Bahamas", "Belarus", "Brazil", "Bulgaria", "Canada", "China", "Croatia", "Cuba", "Egypt", "France", "Germany", "Indonesia", "Ireland", "Jamaica", "Japan", "Luxembourg"}
+
+var IndexResult int
+
+func BenchmarkLookup(b *testing.B) {
+	m := make(map[string]int)
+	for i, c := range capitals {
+		m[c] = i
+	}
+	keys := make([][]byte, len(capitals))
+	for i, c := range capitals {
+		keys[i] = []byte(c)
+	}
+	sorted := append([]string(nil), capitals...)
+	sort.Strings(sorted)
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		var r int
+		for n := 0; n < b.N; n++ {
+			r, _ = Lookup(m, keys[n%len(keys)])
+		}
+		IndexResult = r
+	})
+	b.Run("search", func(b *testing.B) {
+		b.ReportAllocs()
+		var r int
+		for n := 0; n < b.N; n++ {
+			r, _ = Search(sorted, keys[n%len(keys)])
+		}
+		IndexResult = r
+	})
+}
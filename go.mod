@@ -1,10 +1,15 @@
 module github.com/grafana/high-performance-go-workshop
 
-go 1.19
+go 1.25.0
 
-require github.com/pkg/profile v1.7.0
+require (
+	github.com/pkg/profile v1.7.0
+	golang.org/x/tools v0.45.0
+)
 
 require (
 	github.com/felixge/fgprof v0.9.3 // indirect
 	github.com/google/pprof v0.0.0-20211214055906-6f57359322fd // indirect
+	golang.org/x/mod v0.36.0 // indirect
+	golang.org/x/sync v0.20.0 // indirect
 )